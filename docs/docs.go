@@ -15,9 +15,197 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/auth/login": {
+            "post": {
+                "description": "Login with username and password, returns an access token and a refresh token.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Login",
+                "parameters": [
+                    {
+                        "description": "login",
+                        "name": "data",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/entity.LoginRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Data",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/entity.JsonSuccess"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/entity.TokenResponse"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Validation error",
+                        "schema": {
+                            "$ref": "#/definitions/entity.JsonBadRequest"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/entity.JsonUnauthorized"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/entity.JsonInternalServerError"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/logout": {
+            "post": {
+                "description": "Revoke the refresh token and every token rotated from the same login.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Logout",
+                "parameters": [
+                    {
+                        "description": "refresh token",
+                        "name": "data",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/entity.RefreshTokenRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Data",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/entity.JsonSuccess"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "object"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Validation error",
+                        "schema": {
+                            "$ref": "#/definitions/entity.JsonBadRequest"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/entity.JsonUnauthorized"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/entity.JsonInternalServerError"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/refresh": {
+            "post": {
+                "description": "Exchange a refresh token for a new token pair, the presented refresh token is revoked.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Refresh token",
+                "parameters": [
+                    {
+                        "description": "refresh token",
+                        "name": "data",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/entity.RefreshTokenRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Data",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/entity.JsonSuccess"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/entity.TokenResponse"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Validation error",
+                        "schema": {
+                            "$ref": "#/definitions/entity.JsonBadRequest"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/entity.JsonUnauthorized"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/entity.JsonInternalServerError"
+                        }
+                    }
+                }
+            }
+        },
         "/customers": {
             "get": {
-                "description": "Get all customers.",
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Get all customers. Any field can be filtered with filter[field][operator]=value,\noperators are eq, neq, gt, gte, lt, lte, contains (case-insensitive substring), like and ilike (patterns with % and _),\nin (a,b,c), between (a,b) and is_null (true/false), e.g. filter[email][contains]=gmail\u0026filter[created_at][gte]=2024-01-01.",
                 "produces": [
                     "application/json"
                 ],
@@ -67,6 +255,30 @@ const docTemplate = `{
                         "description": "sort",
                         "name": "sort",
                         "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "next_cursor or prev_cursor from a previous page, switches to keyset pagination on the sort columns, NULLs sort as an empty string",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "search username, email, phone and address",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "q match mode: contains (default), prefix, exact, fulltext (ranked), fuzzy (ranked, typo tolerant)",
+                        "name": "mode",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "also list soft deleted customers, requires customers:delete",
+                        "name": "include_deleted",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -97,6 +309,18 @@ const docTemplate = `{
                             "$ref": "#/definitions/entity.JsonBadRequest"
                         }
                     },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/entity.JsonUnauthorized"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/entity.JsonForbidden"
+                        }
+                    },
                     "404": {
                         "description": "Data not found",
                         "schema": {
@@ -112,6 +336,11 @@ const docTemplate = `{
                 }
             },
             "post": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
                 "description": "Create customer.",
                 "produces": [
                     "application/json"
@@ -122,24 +351,28 @@ const docTemplate = `{
                 "summary": "Create customer",
                 "parameters": [
                     {
+                        "maxLength": 125,
                         "type": "string",
                         "name": "address",
                         "in": "formData",
                         "required": true
                     },
                     {
+                        "maxLength": 125,
                         "type": "string",
                         "name": "email",
                         "in": "formData",
                         "required": true
                     },
                     {
+                        "maxLength": 125,
                         "type": "string",
                         "name": "phone",
                         "in": "formData",
                         "required": true
                     },
                     {
+                        "maxLength": 125,
                         "type": "string",
                         "name": "username",
                         "in": "formData",
@@ -171,6 +404,18 @@ const docTemplate = `{
                             "$ref": "#/definitions/entity.JsonBadRequest"
                         }
                     },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/entity.JsonUnauthorized"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/entity.JsonForbidden"
+                        }
+                    },
                     "404": {
                         "description": "Data not found",
                         "schema": {
@@ -188,7 +433,12 @@ const docTemplate = `{
         },
         "/customers/batch": {
             "post": {
-                "description": "Create customer batch.",
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Create customer batch. on_conflict decides what happens to a customer whose email is taken: error (default) fails the batch, skip keeps the existing customer, update overwrites it. With partial=true every customer is created on its own and the response lists the outcome per item.",
                 "produces": [
                     "application/json"
                 ],
@@ -205,6 +455,12 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/entity.CreateCustomerBatchRequest"
                         }
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "process every customer independently",
+                        "name": "partial",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -219,7 +475,28 @@ const docTemplate = `{
                                     "type": "object",
                                     "properties": {
                                         "data": {
-                                            "type": "object"
+                                            "$ref": "#/definitions/entity.BatchResult"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "207": {
+                        "description": "Outcome per item in partial mode",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/entity.JsonMultiStatus"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.BatchItemResult"
+                                            }
                                         }
                                     }
                                 }
@@ -232,6 +509,18 @@ const docTemplate = `{
                             "$ref": "#/definitions/entity.JsonBadRequest"
                         }
                     },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/entity.JsonUnauthorized"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/entity.JsonForbidden"
+                        }
+                    },
                     "404": {
                         "description": "Data not found",
                         "schema": {
@@ -247,7 +536,12 @@ const docTemplate = `{
                 }
             },
             "delete": {
-                "description": "Delete batch customer.",
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Delete batch customer. Customers are soft deleted and can be restored until they are purged. With partial=true every id is deleted on its own and the response lists the outcome per item.",
                 "produces": [
                     "application/json"
                 ],
@@ -264,6 +558,12 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/entity.DeleteBatchCustomerRequest"
                         }
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "process every id independently",
+                        "name": "partial",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -285,12 +585,45 @@ const docTemplate = `{
                             ]
                         }
                     },
+                    "207": {
+                        "description": "Outcome per item in partial mode",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/entity.JsonMultiStatus"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.BatchItemResult"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
                     "400": {
                         "description": "Validation error",
                         "schema": {
                             "$ref": "#/definitions/entity.JsonBadRequest"
                         }
                     },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/entity.JsonUnauthorized"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/entity.JsonForbidden"
+                        }
+                    },
                     "404": {
                         "description": "Data not found",
                         "schema": {
@@ -304,42 +637,701 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/customers/export": {
-            "get": {
-                "description": "Export Excel customer.",
+            },
+            "patch": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Change many customers in one transaction. Send either items, each an id with the fields to change, or a filter (the list filters as JSON) with set, the fields to assign to every match; email cannot be set by filter. With dry_run nothing is written and the response tells how many customers would change.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "customers"
                 ],
-                "summary": "Export Excel customer.",
+                "summary": "Bulk update customers",
                 "parameters": [
                     {
-                        "type": "string",
+                        "description": "bulk update customers",
+                        "name": "data",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/entity.BulkUpdateCustomerRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Data",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/entity.JsonSuccess"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/entity.BulkUpdateResult"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Validation error",
+                        "schema": {
+                            "$ref": "#/definitions/entity.JsonBadRequest"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/entity.JsonUnauthorized"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/entity.JsonForbidden"
+                        }
+                    },
+                    "404": {
+                        "description": "Data not found",
+                        "schema": {
+                            "$ref": "#/definitions/entity.JsonNotFound"
+                        }
+                    },
+                    "412": {
+                        "description": "Customer changed while updating",
+                        "schema": {
+                            "$ref": "#/definitions/entity.JsonPreconditionFailed"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/entity.JsonInternalServerError"
+                        }
+                    }
+                }
+            }
+        },
+        "/customers/export": {
+            "get": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Export customer as xlsx, csv or jsonl. Takes the same filters as the customer list, including filter[field][operator]. The file is streamed as it is read from the database.",
+                "produces": [
+                    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+                    "text/csv",
+                    "application/x-ndjson"
+                ],
+                "tags": [
+                    "customers"
+                ],
+                "summary": "Export customer.",
+                "parameters": [
+                    {
+                        "type": "string",
                         "description": "start_date",
                         "name": "start_date",
                         "in": "query"
                     },
-                    {
-                        "type": "string",
-                        "description": "end_date",
-                        "name": "end_date",
-                        "in": "query"
+                    {
+                        "type": "string",
+                        "description": "end_date",
+                        "name": "end_date",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "username",
+                        "name": "username",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "email",
+                        "name": "email",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "sort",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "search username, email, phone and address",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "q match mode: contains (default), prefix, exact, fulltext (ranked), fuzzy (ranked, typo tolerant)",
+                        "name": "mode",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "also export soft deleted customers, requires customers:delete",
+                        "name": "include_deleted",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "xlsx (default), csv or jsonl",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "csv field delimiter, a single character or tab, default ,",
+                        "name": "delimiter",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "csv encoding: utf-8 (default), utf-8-bom or latin1",
+                        "name": "encoding",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "customer_\u003ctimestamp\u003e.\u003cformat\u003e",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Validation error",
+                        "schema": {
+                            "$ref": "#/definitions/entity.JsonBadRequest"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/entity.JsonUnauthorized"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/entity.JsonForbidden"
+                        }
+                    },
+                    "404": {
+                        "description": "Data not found",
+                        "schema": {
+                            "$ref": "#/definitions/entity.JsonNotFound"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/entity.JsonInternalServerError"
+                        }
+                    }
+                }
+            }
+        },
+        "/customers/exports": {
+            "post": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Queue an export of the customers matching the filter, which takes the list filters as JSON. The file format and its options are query parameters, as for the synchronous export. Poll the job for progress and download the file once it is completed.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "customers"
+                ],
+                "summary": "Create customer export job",
+                "parameters": [
+                    {
+                        "description": "customer filter",
+                        "name": "data",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/entity.CustomerQueryFilter"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "xlsx (default), csv or jsonl",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "csv field delimiter, a single character or tab, default ,",
+                        "name": "delimiter",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "csv encoding: utf-8 (default), utf-8-bom or latin1",
+                        "name": "encoding",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Data",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/entity.JsonSuccess"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/entity.ExportJobResponse"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Validation error",
+                        "schema": {
+                            "$ref": "#/definitions/entity.JsonBadRequest"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/entity.JsonUnauthorized"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/entity.JsonForbidden"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/entity.JsonInternalServerError"
+                        }
+                    }
+                }
+            }
+        },
+        "/customers/exports/{exportId}": {
+            "get": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Get the state and progress of an export job.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "customers"
+                ],
+                "summary": "Get customer export job",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "export_id",
+                        "name": "exportId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Data",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/entity.JsonSuccess"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/entity.ExportJobResponse"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Validation error",
+                        "schema": {
+                            "$ref": "#/definitions/entity.JsonBadRequest"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/entity.JsonUnauthorized"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/entity.JsonForbidden"
+                        }
+                    },
+                    "404": {
+                        "description": "Data not found",
+                        "schema": {
+                            "$ref": "#/definitions/entity.JsonNotFound"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/entity.JsonInternalServerError"
+                        }
+                    }
+                }
+            }
+        },
+        "/customers/exports/{exportId}/download": {
+            "get": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Download the file of a completed export job, files are removed once the job expires. The file is kept by the instance that ran the job, which has to serve the download.",
+                "produces": [
+                    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+                    "text/csv",
+                    "application/x-ndjson"
+                ],
+                "tags": [
+                    "customers"
+                ],
+                "summary": "Download customer export",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "export_id",
+                        "name": "exportId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "customer export",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Export is not completed",
+                        "schema": {
+                            "$ref": "#/definitions/entity.JsonBadRequest"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/entity.JsonUnauthorized"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/entity.JsonForbidden"
+                        }
+                    },
+                    "404": {
+                        "description": "Data not found, expired or kept by another instance",
+                        "schema": {
+                            "$ref": "#/definitions/entity.JsonNotFound"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/entity.JsonInternalServerError"
+                        }
+                    }
+                }
+            }
+        },
+        "/customers/import": {
+            "post": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Import customer from xlsx (sheet MST_CUSTOMER, or the first sheet), csv or jsonl. The format is detected from the file content. Columns are matched by header (username/name, email, phone, address, case-insensitive) in any order. With dry_run=true nothing is written and the data is an entity.ImportPreviewResponse.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "customers"
+                ],
+                "summary": "Import customer.",
+                "parameters": [
+                    {
+                        "type": "file",
+                        "description": "Import customer file",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "error (default), skip or update a customer whose email is taken",
+                        "name": "on_conflict",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "csv field delimiter, a single character or tab, default ,",
+                        "name": "delimiter",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "csv encoding: utf-8 (default, a BOM is skipped) or latin1",
+                        "name": "encoding",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only validate the file and report what the import would do",
+                        "name": "dry_run",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Parsed rows returned by a dry run, 1 to 100, default 10",
+                        "name": "preview",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "json (default) or xlsx: a failed import answers with the file, its invalid cells highlighted and commented and an Import Errors sheet",
+                        "name": "error_report",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Data",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/entity.JsonSuccess"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/entity.BatchResult"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Validation error",
+                        "schema": {
+                            "$ref": "#/definitions/entity.JsonBadRequest"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/entity.JsonUnauthorized"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/entity.JsonForbidden"
+                        }
+                    },
+                    "404": {
+                        "description": "Data not found",
+                        "schema": {
+                            "$ref": "#/definitions/entity.JsonNotFound"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/entity.JsonInternalServerError"
+                        }
+                    }
+                }
+            }
+        },
+        "/customers/import/template": {
+            "get": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "An xlsx with the MST_CUSTOMER sheet and its header ready to fill, cells check the import rules as they are typed. An Instructions sheet describes every column and shows example rows.",
+                "produces": [
+                    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+                ],
+                "tags": [
+                    "customers"
+                ],
+                "summary": "Download the customer import template.",
+                "responses": {
+                    "200": {
+                        "description": "customer_import_template.xlsx",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/entity.JsonUnauthorized"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/entity.JsonForbidden"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/entity.JsonInternalServerError"
+                        }
+                    }
+                }
+            }
+        },
+        "/customers/lookup": {
+            "post": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Fetch up to 1000 customers at once. Found customers come back in the order of the request, ids without a customer are listed in missing.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "customers"
+                ],
+                "summary": "Lookup customers by id",
+                "parameters": [
+                    {
+                        "description": "customer ids",
+                        "name": "data",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/entity.LookupCustomerRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Data",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/entity.JsonSuccess"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/entity.LookupCustomerResponse"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Validation error",
+                        "schema": {
+                            "$ref": "#/definitions/entity.JsonBadRequest"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/entity.JsonUnauthorized"
+                        }
                     },
-                    {
-                        "type": "string",
-                        "description": "username",
-                        "name": "username",
-                        "in": "query"
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/entity.JsonForbidden"
+                        }
                     },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/entity.JsonInternalServerError"
+                        }
+                    }
+                }
+            }
+        },
+        "/customers/purge": {
+            "delete": {
+                "security": [
                     {
-                        "type": "string",
-                        "description": "email",
-                        "name": "email",
-                        "in": "query"
+                        "Bearer": []
+                    }
+                ],
+                "description": "Permanently remove customers, only customers that were deleted before can be purged.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "customers"
+                ],
+                "summary": "Purge batch customer",
+                "parameters": [
+                    {
+                        "description": "purge batch customer",
+                        "name": "data",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/entity.PurgeBatchCustomerRequest"
+                        }
                     }
                 ],
                 "responses": {
@@ -354,7 +1346,7 @@ const docTemplate = `{
                                     "type": "object",
                                     "properties": {
                                         "data": {
-                                            "type": "string"
+                                            "type": "object"
                                         }
                                     }
                                 }
@@ -367,6 +1359,18 @@ const docTemplate = `{
                             "$ref": "#/definitions/entity.JsonBadRequest"
                         }
                     },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/entity.JsonUnauthorized"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/entity.JsonForbidden"
+                        }
+                    },
                     "404": {
                         "description": "Data not found",
                         "schema": {
@@ -382,26 +1386,30 @@ const docTemplate = `{
                 }
             }
         },
-        "/customers/import": {
+        "/customers/restore": {
             "post": {
-                "description": "Import Excel customer.",
-                "consumes": [
-                    "multipart/form-data"
+                "security": [
+                    {
+                        "Bearer": []
+                    }
                 ],
+                "description": "Restore soft deleted customers.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "customers"
                 ],
-                "summary": "Import Excel customer.",
+                "summary": "Restore batch customer",
                 "parameters": [
                     {
-                        "type": "file",
-                        "description": "Import Excel customer",
-                        "name": "file",
-                        "in": "formData",
-                        "required": true
+                        "description": "restore batch customer",
+                        "name": "data",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/entity.RestoreBatchCustomerRequest"
+                        }
                     }
                 ],
                 "responses": {
@@ -416,7 +1424,7 @@ const docTemplate = `{
                                     "type": "object",
                                     "properties": {
                                         "data": {
-                                            "type": "string"
+                                            "type": "object"
                                         }
                                     }
                                 }
@@ -424,11 +1432,23 @@ const docTemplate = `{
                         }
                     },
                     "400": {
-                        "description": "Validation error",
+                        "description": "Validation error, or an email now taken by another customer",
                         "schema": {
                             "$ref": "#/definitions/entity.JsonBadRequest"
                         }
                     },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/entity.JsonUnauthorized"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/entity.JsonForbidden"
+                        }
+                    },
                     "404": {
                         "description": "Data not found",
                         "schema": {
@@ -446,6 +1466,11 @@ const docTemplate = `{
         },
         "/customers/{customerId}": {
             "get": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
                 "description": "get customer by id.",
                 "produces": [
                     "application/json"
@@ -488,6 +1513,18 @@ const docTemplate = `{
                             "$ref": "#/definitions/entity.JsonBadRequest"
                         }
                     },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/entity.JsonUnauthorized"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/entity.JsonForbidden"
+                        }
+                    },
                     "404": {
                         "description": "Data not found",
                         "schema": {
@@ -502,15 +1539,20 @@ const docTemplate = `{
                     }
                 }
             },
-            "patch": {
-                "description": "update customer.",
+            "put": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "replace every editable field of the customer, all fields are required.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "customers"
                 ],
-                "summary": "update customer",
+                "summary": "replace customer",
                 "parameters": [
                     {
                         "description": "update customer",
@@ -527,6 +1569,119 @@ const docTemplate = `{
                         "name": "customerId",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "strong ETag from get customer by id, or * for any version",
+                        "name": "If-Match",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Data",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/entity.JsonSuccess"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "object"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Validation error",
+                        "schema": {
+                            "$ref": "#/definitions/entity.JsonBadRequest"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/entity.JsonUnauthorized"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/entity.JsonForbidden"
+                        }
+                    },
+                    "404": {
+                        "description": "Data not found",
+                        "schema": {
+                            "$ref": "#/definitions/entity.JsonNotFound"
+                        }
+                    },
+                    "412": {
+                        "description": "Customer changed since it was read",
+                        "schema": {
+                            "$ref": "#/definitions/entity.JsonPreconditionFailed"
+                        }
+                    },
+                    "428": {
+                        "description": "If-Match header missing",
+                        "schema": {
+                            "$ref": "#/definitions/entity.JsonPreconditionRequired"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/entity.JsonInternalServerError"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "partially update a customer with a merge patch (application/merge-patch+json, RFC 7396) or a JSON Patch (application/json-patch+json, RFC 6902), only the patched fields are validated.",
+                "consumes": [
+                    "application/merge-patch+json",
+                    "application/json-patch+json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "customers"
+                ],
+                "summary": "patch customer",
+                "parameters": [
+                    {
+                        "description": "merge patch or JSON Patch document",
+                        "name": "data",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "customer_id",
+                        "name": "customerId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "strong ETag from get customer by id, or * for any version",
+                        "name": "If-Match",
+                        "in": "header",
+                        "required": true
                     }
                 ],
                 "responses": {
@@ -554,12 +1709,36 @@ const docTemplate = `{
                             "$ref": "#/definitions/entity.JsonBadRequest"
                         }
                     },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/entity.JsonUnauthorized"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/entity.JsonForbidden"
+                        }
+                    },
                     "404": {
                         "description": "Data not found",
                         "schema": {
                             "$ref": "#/definitions/entity.JsonNotFound"
                         }
                     },
+                    "412": {
+                        "description": "Customer changed since it was read",
+                        "schema": {
+                            "$ref": "#/definitions/entity.JsonPreconditionFailed"
+                        }
+                    },
+                    "428": {
+                        "description": "If-Match header missing",
+                        "schema": {
+                            "$ref": "#/definitions/entity.JsonPreconditionRequired"
+                        }
+                    },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
@@ -568,9 +1747,104 @@ const docTemplate = `{
                     }
                 }
             }
-        }
-    },
-    "definitions": {
+        }
+    },
+    "definitions": {
+        "entity.BatchItemResult": {
+            "type": "object",
+            "properties": {
+                "errors": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "index": {
+                    "type": "integer"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "outcome": {
+                    "type": "string",
+                    "example": "created"
+                }
+            }
+        },
+        "entity.BatchResult": {
+            "type": "object",
+            "properties": {
+                "inserted": {
+                    "type": "integer"
+                },
+                "skipped": {
+                    "type": "integer"
+                },
+                "updated": {
+                    "type": "integer"
+                }
+            }
+        },
+        "entity.BulkUpdateCustomerItem": {
+            "type": "object",
+            "properties": {
+                "fields": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    },
+                    "example": {
+                        "phone": "08123456789"
+                    }
+                },
+                "id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "entity.BulkUpdateCustomerRequest": {
+            "type": "object",
+            "properties": {
+                "dry_run": {
+                    "type": "boolean"
+                },
+                "filter": {
+                    "$ref": "#/definitions/entity.CustomerQueryFilter"
+                },
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/entity.BulkUpdateCustomerItem"
+                    }
+                },
+                "set": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    },
+                    "example": {
+                        "address": "Jakarta"
+                    }
+                }
+            }
+        },
+        "entity.BulkUpdateResult": {
+            "type": "object",
+            "properties": {
+                "changed": {
+                    "type": "integer"
+                },
+                "dry_run": {
+                    "type": "boolean"
+                },
+                "matched": {
+                    "type": "integer"
+                }
+            }
+        },
         "entity.CreateCustomerBatchRequest": {
             "type": "object",
             "required": [
@@ -582,6 +1856,15 @@ const docTemplate = `{
                     "items": {
                         "$ref": "#/definitions/entity.CreateCustomerRequest"
                     }
+                },
+                "on_conflict": {
+                    "type": "string",
+                    "enum": [
+                        "error",
+                        "skip",
+                        "update"
+                    ],
+                    "example": "error"
                 }
             }
         },
@@ -595,12 +1878,70 @@ const docTemplate = `{
             ],
             "properties": {
                 "address": {
+                    "type": "string",
+                    "maxLength": 125
+                },
+                "email": {
+                    "type": "string",
+                    "maxLength": 125
+                },
+                "phone": {
+                    "type": "string",
+                    "maxLength": 125
+                },
+                "username": {
+                    "type": "string",
+                    "maxLength": 125
+                }
+            }
+        },
+        "entity.CustomerQueryFilter": {
+            "type": "object",
+            "properties": {
+                "cursor": {
                     "type": "string"
                 },
                 "email": {
                     "type": "string"
                 },
-                "phone": {
+                "end_date": {
+                    "type": "string"
+                },
+                "filters": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "object",
+                        "additionalProperties": {
+                            "type": "array",
+                            "items": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                },
+                "include_deleted": {
+                    "description": "IncludeDeleted also lists soft deleted customers, handlers only honour it for admins",
+                    "type": "boolean"
+                },
+                "is_active": {
+                    "type": "integer"
+                },
+                "limit": {
+                    "type": "integer"
+                },
+                "mode": {
+                    "type": "string"
+                },
+                "page": {
+                    "type": "integer"
+                },
+                "q": {
+                    "type": "string"
+                },
+                "sort": {
+                    "type": "string"
+                },
+                "start_date": {
                     "type": "string"
                 },
                 "username": {
@@ -617,6 +1958,9 @@ const docTemplate = `{
                 "created_at": {
                     "type": "string"
                 },
+                "deleted_at": {
+                    "type": "string"
+                },
                 "email": {
                     "type": "string"
                 },
@@ -628,6 +1972,9 @@ const docTemplate = `{
                 },
                 "username": {
                     "type": "string"
+                },
+                "version": {
+                    "type": "integer"
                 }
             }
         },
@@ -645,6 +1992,43 @@ const docTemplate = `{
                 }
             }
         },
+        "entity.ExportJobResponse": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "finished_at": {
+                    "type": "string"
+                },
+                "format": {
+                    "type": "string",
+                    "example": "xlsx"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "percent": {
+                    "type": "integer"
+                },
+                "rows_written": {
+                    "type": "integer"
+                },
+                "state": {
+                    "type": "string",
+                    "example": "running"
+                },
+                "total_rows": {
+                    "type": "integer"
+                }
+            }
+        },
         "entity.JsonBadRequest": {
             "type": "object",
             "properties": {
@@ -694,6 +2078,27 @@ const docTemplate = `{
                 }
             }
         },
+        "entity.JsonForbidden": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "integer",
+                    "example": 403
+                },
+                "errors": {
+                    "type": "string",
+                    "example": "missing permission customers:read"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "FORBIDDEN"
+                },
+                "trace_id": {
+                    "type": "string",
+                    "example": "dedc5250-5c20-48c9-9383-fac3ccff2679"
+                }
+            }
+        },
         "entity.JsonInternalServerError": {
             "type": "object",
             "properties": {
@@ -715,6 +2120,24 @@ const docTemplate = `{
                 }
             }
         },
+        "entity.JsonMultiStatus": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "integer",
+                    "example": 207
+                },
+                "data": {},
+                "status": {
+                    "type": "string",
+                    "example": "Multi-Status"
+                },
+                "trace_id": {
+                    "type": "string",
+                    "example": "dedc5250-5c20-48c9-9383-fac3ccff2679"
+                }
+            }
+        },
         "entity.JsonNotFound": {
             "type": "object",
             "properties": {
@@ -736,6 +2159,48 @@ const docTemplate = `{
                 }
             }
         },
+        "entity.JsonPreconditionFailed": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "integer",
+                    "example": 412
+                },
+                "errors": {
+                    "type": "string",
+                    "example": "customer was modified by someone else"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "PRECONDITION FAILED"
+                },
+                "trace_id": {
+                    "type": "string",
+                    "example": "dedc5250-5c20-48c9-9383-fac3ccff2679"
+                }
+            }
+        },
+        "entity.JsonPreconditionRequired": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "integer",
+                    "example": 428
+                },
+                "errors": {
+                    "type": "string",
+                    "example": "If-Match header with the customer ETag is required"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "PRECONDITION REQUIRED"
+                },
+                "trace_id": {
+                    "type": "string",
+                    "example": "dedc5250-5c20-48c9-9383-fac3ccff2679"
+                }
+            }
+        },
         "entity.JsonSuccess": {
             "type": "object",
             "properties": {
@@ -758,15 +2223,89 @@ const docTemplate = `{
                 }
             }
         },
+        "entity.JsonUnauthorized": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "integer",
+                    "example": 401
+                },
+                "errors": {
+                    "type": "string",
+                    "example": "missing or malformed bearer token"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "UNAUTHORIZED"
+                },
+                "trace_id": {
+                    "type": "string",
+                    "example": "dedc5250-5c20-48c9-9383-fac3ccff2679"
+                }
+            }
+        },
+        "entity.LoginRequest": {
+            "type": "object",
+            "required": [
+                "password",
+                "username"
+            ],
+            "properties": {
+                "password": {
+                    "type": "string"
+                },
+                "username": {
+                    "type": "string"
+                }
+            }
+        },
+        "entity.LookupCustomerRequest": {
+            "type": "object",
+            "required": [
+                "id"
+            ],
+            "properties": {
+                "id": {
+                    "type": "array",
+                    "maxItems": 1000,
+                    "items": {
+                        "type": "integer"
+                    }
+                }
+            }
+        },
+        "entity.LookupCustomerResponse": {
+            "type": "object",
+            "properties": {
+                "customers": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/entity.CustomerResponse"
+                    }
+                },
+                "missing": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                }
+            }
+        },
         "entity.Meta": {
             "type": "object",
             "properties": {
                 "limit": {
                     "type": "integer"
                 },
+                "next_cursor": {
+                    "type": "string"
+                },
                 "page": {
                     "type": "integer"
                 },
+                "prev_cursor": {
+                    "type": "string"
+                },
                 "total_data": {
                     "type": "integer"
                 },
@@ -775,6 +2314,31 @@ const docTemplate = `{
                 }
             }
         },
+        "entity.PurgeBatchCustomerRequest": {
+            "type": "object",
+            "required": [
+                "id"
+            ],
+            "properties": {
+                "id": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                }
+            }
+        },
+        "entity.RefreshTokenRequest": {
+            "type": "object",
+            "required": [
+                "refresh_token"
+            ],
+            "properties": {
+                "refresh_token": {
+                    "type": "string"
+                }
+            }
+        },
         "entity.Response": {
             "type": "object",
             "properties": {
@@ -796,6 +2360,37 @@ const docTemplate = `{
                 }
             }
         },
+        "entity.RestoreBatchCustomerRequest": {
+            "type": "object",
+            "required": [
+                "id"
+            ],
+            "properties": {
+                "id": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                }
+            }
+        },
+        "entity.TokenResponse": {
+            "type": "object",
+            "properties": {
+                "access_token": {
+                    "type": "string"
+                },
+                "expires_in": {
+                    "type": "integer"
+                },
+                "refresh_token": {
+                    "type": "string"
+                },
+                "token_type": {
+                    "type": "string"
+                }
+            }
+        },
         "entity.UpdateCustomerRequest": {
             "type": "object",
             "required": [
@@ -807,19 +2402,23 @@ const docTemplate = `{
             ],
             "properties": {
                 "address": {
-                    "type": "string"
+                    "type": "string",
+                    "maxLength": 125
                 },
                 "email": {
-                    "type": "string"
+                    "type": "string",
+                    "maxLength": 125
                 },
                 "id": {
                     "type": "integer"
                 },
                 "phone": {
-                    "type": "string"
+                    "type": "string",
+                    "maxLength": 125
                 },
                 "username": {
-                    "type": "string"
+                    "type": "string",
+                    "maxLength": 125
                 }
             }
         }
@@ -24,10 +24,50 @@ type Paging struct {
 }
 
 type Meta struct {
-	Limit     int `json:"limit"`
-	Page      int `json:"page"`
-	TotalData int `json:"total_data"`
-	TotalPage int `json:"total_page"`
+	Limit      int    `json:"limit"`
+	Page       int    `json:"page"`
+	TotalData  int    `json:"total_data"`
+	TotalPage  int    `json:"total_page"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+}
+
+// FileFormatRequest picks the format of a file download, Delimiter and Encoding only apply to csv.
+type FileFormatRequest struct {
+	Format    string `query:"format"`
+	Delimiter string `query:"delimiter"`
+	Encoding  string `query:"encoding"`
+}
+
+// On conflict modes for batch inserts, decide what happens to a row whose unique key is taken.
+const (
+	OnConflictError  = "error"
+	OnConflictSkip   = "skip"
+	OnConflictUpdate = "update"
+)
+
+type BatchResult struct {
+	Inserted int `json:"inserted"`
+	Updated  int `json:"updated"`
+	Skipped  int `json:"skipped"`
+}
+
+// Outcomes of one item in a partial batch.
+const (
+	BatchOutcomeCreated  = "created"
+	BatchOutcomeDeleted  = "deleted"
+	BatchOutcomeInvalid  = "invalid"
+	BatchOutcomeNotFound = "not_found"
+	BatchOutcomeFailed   = "failed"
+)
+
+// BatchItemResult is the outcome of the item at Index of a batch processed in partial mode.
+type BatchItemResult struct {
+	Index   int               `json:"index"`
+	Outcome string            `json:"outcome" example:"created"`
+	ID      int               `json:"id,omitempty"`
+	Errors  map[string]string `json:"errors,omitempty"`
+	Message string            `json:"message,omitempty"`
 }
 
 //
@@ -38,11 +78,16 @@ type Meta struct {
 //	}
 //}
 
+// GeneralQueryFilter holds the list parameters shared by every entity. Filters is not bound by
+// echo from a query string, handlers fill it from the filter[field][operator] query keys; in a
+// JSON body it is {"field": {"operator": ["value"]}}.
 type GeneralQueryFilter struct {
-	Page     int    `query:"page"`
-	Limit    int    `query:"limit" validate:"required"`
-	Query    string `query:"q"`
-	Mode     string `query:"mode"`
-	Sort     string `query:"sort"`
-	IsActive int    `query:"is_active"`
+	Page     int                            `query:"page" json:"page,omitempty"`
+	Limit    int                            `query:"limit" json:"limit,omitempty"`
+	Query    string                         `query:"q" json:"q,omitempty"`
+	Mode     string                         `query:"mode" json:"mode,omitempty"`
+	Sort     string                         `query:"sort" json:"sort,omitempty"`
+	Cursor   string                         `query:"cursor" json:"cursor,omitempty"`
+	IsActive *int                           `query:"is_active" json:"is_active,omitempty"`
+	Filters  map[string]map[string][]string `json:"filters,omitempty"`
 }
@@ -0,0 +1,26 @@
+package entity
+
+type LoginRequest struct {
+	Username string `json:"username" validate:"required"`
+	Password string `json:"password" validate:"required"`
+}
+
+// CreateUserRequest is a user created outside the API, such as the admin set up on startup.
+type CreateUserRequest struct {
+	Username string `json:"username" validate:"required,max=125"`
+	Email    string `json:"email" validate:"omitempty,email,max=125"`
+	// bcrypt ignores anything past 72 bytes
+	Password string `json:"password" validate:"required,min=8,maxBytes=72"`
+	Role     string `json:"role" validate:"required"`
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+}
@@ -3,39 +3,116 @@ package entity
 import "mime/multipart"
 
 type CustomerResponse struct {
-	ID        int    `json:"id"`
-	Username  string `json:"username"`
-	Email     string `json:"email"`
-	Phone     string `json:"phone"`
-	Address   string `json:"address"`
-	CreatedAt string `json:"created_at"`
+	ID        int     `json:"id"`
+	Username  string  `json:"username"`
+	Email     string  `json:"email"`
+	Phone     string  `json:"phone"`
+	Address   string  `json:"address"`
+	Version   int     `json:"version"`
+	CreatedAt string  `json:"created_at"`
+	DeletedAt *string `json:"deleted_at,omitempty"`
 }
 
 type CreateCustomerBatchRequest struct {
-	Customers []CreateCustomerRequest `json:"customers" validate:"required,dive"`
+	OnConflict string                  `json:"on_conflict" validate:"omitempty,oneof=error skip update" example:"error"`
+	Customers  []CreateCustomerRequest `json:"customers" validate:"required,dive"`
 }
 
 type CreateCustomerRequest struct {
-	Username string `json:"username" validate:"required"`
-	Email    string `json:"email" validate:"required,unique=customers;email"`
-	Phone    string `json:"phone" validate:"required"`
-	Address  string `json:"address" validate:"required"`
+	Username string `json:"username" validate:"required,max=125"`
+	Email    string `json:"email" validate:"required,email,max=125,unique=customers;email"`
+	Phone    string `json:"phone" validate:"required,max=125"`
+	Address  string `json:"address" validate:"required,max=125"`
 }
 
 type UpdateCustomerRequest struct {
 	ID       int    `json:"id" validate:"required"`
-	Username string `json:"username" validate:"required"`
-	Email    string `json:"email" validate:"required,unique=customers;email;id"`
-	Phone    string `json:"phone" validate:"required"`
-	Address  string `json:"address" validate:"required"`
+	Version  int    `json:"-"`
+	Username string `json:"username" validate:"required,max=125"`
+	Email    string `json:"email" validate:"required,email,max=125,unique=customers;email;id"`
+	Phone    string `json:"phone" validate:"required,max=125"`
+	Address  string `json:"address" validate:"required,max=125"`
+}
+
+// PatchCustomerRequest carries a raw RFC 7396 merge patch or RFC 6902 JSON Patch document,
+// ContentType tells which one.
+type PatchCustomerRequest struct {
+	ID          int
+	Version     int
+	ContentType string
+	Patch       []byte
 }
 
 type DeleteBatchCustomerRequest struct {
 	ID []int `json:"id" validate:"required,notEmptyIntSlice"`
 }
 
+type RestoreBatchCustomerRequest struct {
+	ID []int `json:"id" validate:"required,notEmptyIntSlice"`
+}
+
+type PurgeBatchCustomerRequest struct {
+	ID []int `json:"id" validate:"required,notEmptyIntSlice"`
+}
+
+// UploadCustomerRequest is an import file, its format is detected from the content. Delimiter and
+// Encoding only apply to csv.
 type UploadCustomerRequest struct {
-	File *multipart.FileHeader `form:"file" json:"file" validate:"allowedMimeTypeExcel"`
+	File       *multipart.FileHeader `form:"file" json:"file" validate:"required"`
+	OnConflict string                `form:"on_conflict" json:"on_conflict" validate:"omitempty,oneof=error skip update"`
+	Delimiter  string                `form:"delimiter" json:"delimiter"`
+	Encoding   string                `form:"encoding" json:"encoding"`
+	// DryRun only reports what the import would do, PreviewRows is how many parsed rows it returns
+	DryRun      bool `query:"dry_run" form:"dry_run" json:"dry_run"`
+	PreviewRows int  `query:"preview" form:"preview" json:"preview" validate:"omitempty,min=1,max=100"`
+	// ErrorReport xlsx answers a failed import with the file annotated with its errors instead of json
+	ErrorReport string `query:"error_report" form:"error_report" json:"error_report" validate:"omitempty,oneof=json xlsx"`
+}
+
+// Formats of the errors of a failed import.
+const (
+	ImportErrorReportJson = "json"
+	ImportErrorReportXlsx = "xlsx"
+)
+
+// What a dry run import would do with a row.
+const (
+	ImportActionInsert  = "insert"
+	ImportActionUpdate  = "update"
+	ImportActionSkip    = "skip"
+	ImportActionInvalid = "invalid"
+)
+
+type ImportPreviewRow struct {
+	Row      int                 `json:"row"`
+	Action   string              `json:"action"`
+	Username string              `json:"username"`
+	Email    string              `json:"email"`
+	Phone    string              `json:"phone"`
+	Address  string              `json:"address"`
+	Errors   map[string][]string `json:"errors,omitempty"`
+}
+
+// ImportPreviewResponse is the outcome of a dry run import, nothing has been written.
+type ImportPreviewResponse struct {
+	RowsValid   int                 `json:"rows_valid"`
+	RowsInvalid int                 `json:"rows_invalid"`
+	WouldInsert int                 `json:"would_insert"`
+	WouldUpdate int                 `json:"would_update"`
+	WouldSkip   int                 `json:"would_skip"`
+	Errors      map[string][]string `json:"errors,omitempty"`
+	Rows        []ImportPreviewRow  `json:"rows"`
+}
+
+type LookupCustomerRequest struct {
+	ID []int `json:"id" validate:"required,notEmptyIntSlice,max=1000"`
+}
+
+// LookupCustomerResponse lists the found customers in the order they were asked for, ids that
+// match no customer are listed in Missing.
+type LookupCustomerResponse struct {
+	Customers []CustomerResponse `json:"customers"`
+	Missing   []int              `json:"missing"`
 }
 
 type CustomerParams struct {
@@ -43,11 +120,38 @@ type CustomerParams struct {
 }
 
 type CustomerQueryFilter struct {
-	Limit     int    `query:"limit"`
-	Page      int    `query:"page"`
-	StartDate string `query:"start_date"`
-	EndDate   string `query:"end_date"`
-	Username  string `query:"username"`
-	Email     string `query:"email"`
-	Sort      string `query:"sort"`
+	GeneralQueryFilter
+	StartDate string `query:"start_date" json:"start_date,omitempty"`
+	EndDate   string `query:"end_date" json:"end_date,omitempty"`
+	Username  string `query:"username" json:"username,omitempty"`
+	Email     string `query:"email" json:"email,omitempty"`
+	// IncludeDeleted also lists soft deleted customers, handlers only honour it for admins
+	IncludeDeleted bool `query:"include_deleted" json:"include_deleted,omitempty"`
+}
+
+// BulkUpdateCustomerRequest changes many customers in one transaction, either Items, each naming
+// a customer and the fields to change, or every customer matching Filter with the Set assignments.
+// Fields and Set are keyed by the json field names of UpdateCustomerRequest.
+type BulkUpdateCustomerRequest struct {
+	Items  []BulkUpdateCustomerItem `json:"items,omitempty"`
+	Filter *CustomerQueryFilter     `json:"filter,omitempty"`
+	Set    map[string]string        `json:"set,omitempty" example:"address:Jakarta"`
+	DryRun bool                     `json:"dry_run"`
+}
+
+type BulkUpdateCustomerItem struct {
+	ID     int               `json:"id"`
+	Fields map[string]string `json:"fields" example:"phone:08123456789"`
+}
+
+// BulkUpdateCustomerValidation holds the customers a bulk update turns its items into, so
+// validation errors come back keyed as items[index].field.
+type BulkUpdateCustomerValidation struct {
+	Items []UpdateCustomerRequest `json:"items" validate:"dive"`
+}
+
+type BulkUpdateResult struct {
+	Matched int  `json:"matched"`
+	Changed int  `json:"changed"`
+	DryRun  bool `json:"dry_run"`
 }
@@ -23,6 +23,13 @@ type JsonCreated struct {
 	TraceID string      `json:"trace_id" example:"dedc5250-5c20-48c9-9383-fac3ccff2679"`
 }
 
+type JsonMultiStatus struct {
+	Code    int         `json:"code" example:"207"`
+	Status  string      `json:"status" example:"Multi-Status"`
+	Data    interface{} `json:"data,omitempty"`
+	TraceID string      `json:"trace_id" example:"dedc5250-5c20-48c9-9383-fac3ccff2679"`
+}
+
 type JsonInternalServerError struct {
 	Code    int    `json:"code" example:"500"`
 	Status  string `json:"status" example:"INTERNAL SERVER ERROR"`
@@ -36,3 +43,31 @@ type JsonNotFound struct {
 	Errors  string `json:"errors,omitempty" example:"record not found"`
 	TraceID string `json:"trace_id" example:"dedc5250-5c20-48c9-9383-fac3ccff2679"`
 }
+
+type JsonUnauthorized struct {
+	Code    int    `json:"code" example:"401"`
+	Status  string `json:"status" example:"UNAUTHORIZED"`
+	Errors  string `json:"errors,omitempty" example:"missing or malformed bearer token"`
+	TraceID string `json:"trace_id" example:"dedc5250-5c20-48c9-9383-fac3ccff2679"`
+}
+
+type JsonForbidden struct {
+	Code    int    `json:"code" example:"403"`
+	Status  string `json:"status" example:"FORBIDDEN"`
+	Errors  string `json:"errors,omitempty" example:"missing permission customers:read"`
+	TraceID string `json:"trace_id" example:"dedc5250-5c20-48c9-9383-fac3ccff2679"`
+}
+
+type JsonPreconditionFailed struct {
+	Code    int    `json:"code" example:"412"`
+	Status  string `json:"status" example:"PRECONDITION FAILED"`
+	Errors  string `json:"errors,omitempty" example:"customer was modified by someone else"`
+	TraceID string `json:"trace_id" example:"dedc5250-5c20-48c9-9383-fac3ccff2679"`
+}
+
+type JsonPreconditionRequired struct {
+	Code    int    `json:"code" example:"428"`
+	Status  string `json:"status" example:"PRECONDITION REQUIRED"`
+	Errors  string `json:"errors,omitempty" example:"If-Match header with the customer ETag is required"`
+	TraceID string `json:"trace_id" example:"dedc5250-5c20-48c9-9383-fac3ccff2679"`
+}
@@ -0,0 +1,18 @@
+package entity
+
+type ExportJobParams struct {
+	ExportId string `param:"exportId" validate:"required,uuid"`
+}
+
+type ExportJobResponse struct {
+	ID          string  `json:"id"`
+	State       string  `json:"state" example:"running"`
+	Format      string  `json:"format" example:"xlsx"`
+	TotalRows   int     `json:"total_rows"`
+	RowsWritten int     `json:"rows_written"`
+	Percent     int     `json:"percent"`
+	Error       string  `json:"error,omitempty"`
+	CreatedAt   string  `json:"created_at"`
+	FinishedAt  *string `json:"finished_at,omitempty"`
+	ExpiresAt   *string `json:"expires_at,omitempty"`
+}
@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"context"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"scylla/entity"
+	"scylla/pkg/helper"
+	"scylla/pkg/utils"
+	"scylla/usecase"
+	"time"
+)
+
+type AuthHandler struct {
+	authUsecase usecase.AuthUsecase
+}
+
+func NewAuthHandler(authUsecase usecase.AuthUsecase) *AuthHandler {
+	return &AuthHandler{
+		authUsecase: authUsecase,
+	}
+}
+
+// Note            godoc
+//
+// @Summary		Login
+// @Description	Login with username and password, returns an access token and a refresh token.
+// @Param		data	body	entity.LoginRequest	true	"login"
+// @Produce		application/json
+// @Tags		auth
+// @Success		200	{object}	entity.JsonSuccess{data=entity.TokenResponse{}}	"Data"
+// @Failure		400	{object}	entity.JsonBadRequest{}							"Validation error"
+// @Failure		401	{object}	entity.JsonUnauthorized{}						"Unauthorized"
+// @Failure		500	{object}	entity.JsonInternalServerError{}				"Internal server error"
+// @Router		/auth/login [post]
+func (handler *AuthHandler) Login(ctx echo.Context) error {
+	c, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	request := new(entity.LoginRequest)
+	err := ctx.Bind(request)
+	helper.ErrorPanic(err)
+
+	data := handler.authUsecase.Login(c, *request)
+
+	webResponse := entity.Response{
+		Code:   http.StatusOK,
+		Status: "OK",
+		Data:   data,
+	}
+	utils.ResponseInterceptor(ctx, &webResponse)
+	return ctx.JSON(http.StatusOK, webResponse)
+}
+
+// Note            godoc
+//
+// @Summary		Refresh token
+// @Description	Exchange a refresh token for a new token pair, the presented refresh token is revoked.
+// @Param		data	body	entity.RefreshTokenRequest	true	"refresh token"
+// @Produce		application/json
+// @Tags		auth
+// @Success		200	{object}	entity.JsonSuccess{data=entity.TokenResponse{}}	"Data"
+// @Failure		400	{object}	entity.JsonBadRequest{}							"Validation error"
+// @Failure		401	{object}	entity.JsonUnauthorized{}						"Unauthorized"
+// @Failure		500	{object}	entity.JsonInternalServerError{}				"Internal server error"
+// @Router		/auth/refresh [post]
+func (handler *AuthHandler) Refresh(ctx echo.Context) error {
+	c, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	request := new(entity.RefreshTokenRequest)
+	err := ctx.Bind(request)
+	helper.ErrorPanic(err)
+
+	data := handler.authUsecase.Refresh(c, *request)
+
+	webResponse := entity.Response{
+		Code:   http.StatusOK,
+		Status: "OK",
+		Data:   data,
+	}
+	utils.ResponseInterceptor(ctx, &webResponse)
+	return ctx.JSON(http.StatusOK, webResponse)
+}
+
+// Note            godoc
+//
+// @Summary		Logout
+// @Description	Revoke the refresh token and every token rotated from the same login.
+// @Param		data	body	entity.RefreshTokenRequest	true	"refresh token"
+// @Produce		application/json
+// @Tags		auth
+// @Success		200	{object}	entity.JsonSuccess{data=nil}		"Data"
+// @Failure		400	{object}	entity.JsonBadRequest{}				"Validation error"
+// @Failure		401	{object}	entity.JsonUnauthorized{}			"Unauthorized"
+// @Failure		500	{object}	entity.JsonInternalServerError{}	"Internal server error"
+// @Router		/auth/logout [post]
+func (handler *AuthHandler) Logout(ctx echo.Context) error {
+	c, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	request := new(entity.RefreshTokenRequest)
+	err := ctx.Bind(request)
+	helper.ErrorPanic(err)
+
+	handler.authUsecase.Logout(c, *request)
+
+	webResponse := entity.Response{
+		Code:    http.StatusOK,
+		Status:  "OK",
+		Message: "Logout Successful",
+	}
+	utils.ResponseInterceptor(ctx, &webResponse)
+	return ctx.JSON(http.StatusOK, webResponse)
+}
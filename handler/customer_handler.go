@@ -1,17 +1,25 @@
 package handler
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"github.com/labstack/echo/v4"
+	"io"
+	"mime"
 	"net/http"
-	"os"
 	"path/filepath"
 	"scylla/entity"
 	"scylla/pkg/exception"
 	"scylla/pkg/helper"
+	"scylla/pkg/middlewares"
+	"scylla/pkg/querybuilder"
+	"scylla/pkg/tabular"
 	"scylla/pkg/utils"
+	"scylla/repo"
 	"scylla/usecase"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -32,8 +40,11 @@ func NewCustomerHandler(customerUsecase usecase.CustomerUsecase) *CustomerHandle
 // @Param		data	formData	entity.CreateCustomerRequest	true	"create customer"
 // @Produce		application/json
 // @Tags		customers
+// @Security		Bearer
 // @Success		201	{object}	entity.JsonCreated{data=nil}"Data"
 // @Failure		400	{object}	entity.JsonBadRequest{}				"Validation error"
+// @Failure		401	{object}	entity.JsonUnauthorized{}			"Unauthorized"
+// @Failure		403	{object}	entity.JsonForbidden{}			"Forbidden"
 // @Failure		404	{object}	entity.JsonNotFound{}				"Data not found"
 // @Failure		500	{object}	entity.JsonInternalServerError{}	"Internal server error"
 // @Router		/customers [post]
@@ -59,12 +70,17 @@ func (handler *CustomerHandler) Create(ctx echo.Context) error {
 // Note            godoc
 //
 // @Summary		Create customer batch
-// @Description	Create customer batch.
+// @Description	Create customer batch. on_conflict decides what happens to a customer whose email is taken: error (default) fails the batch, skip keeps the existing customer, update overwrites it. With partial=true every customer is created on its own and the response lists the outcome per item.
 // @Param		data	body	entity.CreateCustomerBatchRequest	true	"create customer batch"
+// @Param		partial	query	bool								false	"process every customer independently"
 // @Produce		application/json
 // @Tags		customers
-// @Success		201	{object}	entity.JsonCreated{data=nil}"Data"
+// @Security		Bearer
+// @Success		201	{object}	entity.JsonCreated{data=entity.BatchResult{}}"Data"
+// @Success		207	{object}	entity.JsonMultiStatus{data=[]entity.BatchItemResult{}}"Outcome per item in partial mode"
 // @Failure		400	{object}	entity.JsonBadRequest{}				"Validation error"
+// @Failure		401	{object}	entity.JsonUnauthorized{}			"Unauthorized"
+// @Failure		403	{object}	entity.JsonForbidden{}			"Forbidden"
 // @Failure		404	{object}	entity.JsonNotFound{}				"Data not found"
 // @Failure		500	{object}	entity.JsonInternalServerError{}	"Internal server error"
 // @Router		/customers/batch [post]
@@ -76,12 +92,17 @@ func (handler *CustomerHandler) CreateBatch(ctx echo.Context) error {
 	err := ctx.Bind(request)
 	helper.ErrorPanic(err)
 
-	handler.customerUsecase.CreateBatch(c, *request)
+	if partialMode(ctx) {
+		return handler.multiStatus(ctx, handler.customerUsecase.CreateBatchPartial(c, *request))
+	}
+
+	data := handler.customerUsecase.CreateBatch(c, *request)
 
 	webResponse := entity.Response{
 		Code:    http.StatusCreated,
 		Status:  "Created",
 		Message: "Created Batch Successful",
+		Data:    data,
 	}
 	utils.ResponseInterceptor(ctx, &webResponse)
 	return ctx.JSON(http.StatusCreated, webResponse)
@@ -89,17 +110,23 @@ func (handler *CustomerHandler) CreateBatch(ctx echo.Context) error {
 
 // Note            godoc
 //
-// @Summary		update customer
-// @Description	update customer.
+// @Summary		replace customer
+// @Description	replace every editable field of the customer, all fields are required.
 // @Param		data		body	entity.UpdateCustomerRequest	true	"update customer"
 // @Param		customerId	path	string							true	"customer_id"
+// @Param		If-Match	header	string							true	"strong ETag from get customer by id, or * for any version"
 // @Produce		application/json
 // @Tags		customers
+// @Security		Bearer
 // @Success		200	{object}	entity.JsonSuccess{data=nil}		"Data"
 // @Failure		400	{object}	entity.JsonBadRequest{}				"Validation error"
+// @Failure		401	{object}	entity.JsonUnauthorized{}			"Unauthorized"
+// @Failure		403	{object}	entity.JsonForbidden{}			"Forbidden"
 // @Failure		404	{object}	entity.JsonNotFound{}				"Data not found"
+// @Failure		412	{object}	entity.JsonPreconditionFailed{}		"Customer changed since it was read"
+// @Failure		428	{object}	entity.JsonPreconditionRequired{}	"If-Match header missing"
 // @Failure		500	{object}	entity.JsonInternalServerError{}	"Internal server error"
-// @Router		/customers/{customerId} [patch]
+// @Router		/customers/{customerId} [put]
 func (handler *CustomerHandler) Update(ctx echo.Context) error {
 	c, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
@@ -118,7 +145,10 @@ func (handler *CustomerHandler) Update(ctx echo.Context) error {
 
 	request.ID = params.CustomerId
 
-	handler.customerUsecase.Update(c, *request)
+	request.Version = ifMatchVersion(ctx)
+
+	version := handler.customerUsecase.Update(c, *request)
+	ctx.Response().Header().Set("ETag", utils.ETag(version))
 
 	webResponse := entity.Response{
 		Code:    http.StatusOK,
@@ -127,18 +157,158 @@ func (handler *CustomerHandler) Update(ctx echo.Context) error {
 		Data:    nil,
 	}
 	utils.ResponseInterceptor(ctx, &webResponse)
-	return ctx.JSON(http.StatusCreated, webResponse)
+	return ctx.JSON(http.StatusOK, webResponse)
+}
+
+// Note            godoc
+//
+// @Summary		Lookup customers by id
+// @Description	Fetch up to 1000 customers at once. Found customers come back in the order of the request, ids without a customer are listed in missing.
+// @Param		data	body	entity.LookupCustomerRequest	true	"customer ids"
+// @Produce		application/json
+// @Tags		customers
+// @Security		Bearer
+// @Success		200	{object}	entity.JsonSuccess{data=entity.LookupCustomerResponse{}}	"Data"
+// @Failure		400	{object}	entity.JsonBadRequest{}									"Validation error"
+// @Failure		401	{object}	entity.JsonUnauthorized{}								"Unauthorized"
+// @Failure		403	{object}	entity.JsonForbidden{}									"Forbidden"
+// @Failure		500	{object}	entity.JsonInternalServerError{}						"Internal server error"
+// @Router		/customers/lookup [post]
+func (handler *CustomerHandler) Lookup(ctx echo.Context) error {
+	c, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	request := new(entity.LookupCustomerRequest)
+	err := ctx.Bind(request)
+	helper.ErrorPanic(err)
+
+	data := handler.customerUsecase.Lookup(c, *request)
+
+	webResponse := entity.Response{
+		Code:   http.StatusOK,
+		Status: "OK",
+		Data:   data,
+	}
+	utils.ResponseInterceptor(ctx, &webResponse)
+	return ctx.JSON(http.StatusOK, webResponse)
+}
+
+// Note            godoc
+//
+// @Summary		Bulk update customers
+// @Description	Change many customers in one transaction. Send either items, each an id with the fields to change, or a filter (the list filters as JSON) with set, the fields to assign to every match; email cannot be set by filter. With dry_run nothing is written and the response tells how many customers would change.
+// @Param		data	body	entity.BulkUpdateCustomerRequest	true	"bulk update customers"
+// @Produce		application/json
+// @Tags		customers
+// @Security		Bearer
+// @Success		200	{object}	entity.JsonSuccess{data=entity.BulkUpdateResult{}}	"Data"
+// @Failure		400	{object}	entity.JsonBadRequest{}								"Validation error"
+// @Failure		401	{object}	entity.JsonUnauthorized{}							"Unauthorized"
+// @Failure		403	{object}	entity.JsonForbidden{}								"Forbidden"
+// @Failure		404	{object}	entity.JsonNotFound{}								"Data not found"
+// @Failure		412	{object}	entity.JsonPreconditionFailed{}						"Customer changed while updating"
+// @Failure		500	{object}	entity.JsonInternalServerError{}					"Internal server error"
+// @Router		/customers/batch [patch]
+func (handler *CustomerHandler) BulkUpdate(ctx echo.Context) error {
+	c, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	request := new(entity.BulkUpdateCustomerRequest)
+	err := ctx.Bind(request)
+	helper.ErrorPanic(err)
+
+	data := handler.customerUsecase.BulkUpdate(c, *request)
+
+	webResponse := entity.Response{
+		Code:    http.StatusOK,
+		Status:  "OK",
+		Message: "Bulk Update Successful",
+		Data:    data,
+	}
+	if request.DryRun {
+		webResponse.Message = "Dry Run Successful"
+	}
+	utils.ResponseInterceptor(ctx, &webResponse)
+	return ctx.JSON(http.StatusOK, webResponse)
+}
+
+// Note            godoc
+//
+// @Summary		patch customer
+// @Description	partially update a customer with a merge patch (application/merge-patch+json, RFC 7396) or a JSON Patch (application/json-patch+json, RFC 6902), only the patched fields are validated.
+// @Param		data		body	object	true	"merge patch or JSON Patch document"
+// @Param		customerId	path	string	true	"customer_id"
+// @Param		If-Match	header	string	true	"strong ETag from get customer by id, or * for any version"
+// @Accept		application/merge-patch+json,application/json-patch+json
+// @Produce		application/json
+// @Tags		customers
+// @Security		Bearer
+// @Success		200	{object}	entity.JsonSuccess{data=nil}		"Data"
+// @Failure		400	{object}	entity.JsonBadRequest{}				"Validation error"
+// @Failure		401	{object}	entity.JsonUnauthorized{}			"Unauthorized"
+// @Failure		403	{object}	entity.JsonForbidden{}			"Forbidden"
+// @Failure		404	{object}	entity.JsonNotFound{}				"Data not found"
+// @Failure		412	{object}	entity.JsonPreconditionFailed{}		"Customer changed since it was read"
+// @Failure		428	{object}	entity.JsonPreconditionRequired{}	"If-Match header missing"
+// @Failure		500	{object}	entity.JsonInternalServerError{}	"Internal server error"
+// @Router		/customers/{customerId} [patch]
+func (handler *CustomerHandler) Patch(ctx echo.Context) error {
+	c, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	params := new(entity.CustomerParams)
+	if err := (&echo.DefaultBinder{}).BindPathParams(ctx, params); err != nil {
+		ctx.Logger().Error("Handler : Param ID error : ", err.Error())
+		panic(exception.NewBadRequestHandler(err.Error()))
+	}
+
+	contentType, _, err := mime.ParseMediaType(ctx.Request().Header.Get(echo.HeaderContentType))
+	if err != nil {
+		panic(exception.NewBadRequestHandler("Content-Type header is required"))
+	}
+	switch contentType {
+	case "application/merge-patch+json", "application/json-patch+json", echo.MIMEApplicationJSON:
+	default:
+		panic(exception.NewBadRequestHandler(fmt.Sprintf("unsupported patch type '%s'", contentType)))
+	}
+
+	body, err := io.ReadAll(ctx.Request().Body)
+	if err != nil {
+		panic(exception.NewBadRequestHandler(err.Error()))
+	}
+
+	version := handler.customerUsecase.Patch(c, entity.PatchCustomerRequest{
+		ID:          params.CustomerId,
+		Version:     ifMatchVersion(ctx),
+		ContentType: contentType,
+		Patch:       body,
+	})
+	ctx.Response().Header().Set("ETag", utils.ETag(version))
+
+	webResponse := entity.Response{
+		Code:    http.StatusOK,
+		Status:  "OK",
+		Message: "Update Successful",
+		Data:    nil,
+	}
+	utils.ResponseInterceptor(ctx, &webResponse)
+	return ctx.JSON(http.StatusOK, webResponse)
 }
 
 // Note             godoc
 //
 //	 @Summary		Delete batch customer
-//		@Description	Delete batch customer.
+//		@Description	Delete batch customer. Customers are soft deleted and can be restored until they are purged. With partial=true every id is deleted on its own and the response lists the outcome per item.
 //		@Param			data	body	entity.DeleteBatchCustomerRequest	true	"delete batch customer"
+//		@Param			partial	query	bool								false	"process every id independently"
 //		@Produce		application/json
 //		@Tags			customers
+//		@Security		Bearer
 //		@Success		200	{object}	entity.JsonSuccess{data=nil}		"Data"
+//		@Success		207	{object}	entity.JsonMultiStatus{data=[]entity.BatchItemResult{}}	"Outcome per item in partial mode"
 //		@Failure		400	{object}	entity.JsonBadRequest{}				"Validation error"
+//		@Failure		401	{object}	entity.JsonUnauthorized{}			"Unauthorized"
+//		@Failure		403	{object}	entity.JsonForbidden{}			"Forbidden"
 //		@Failure		404	{object}	entity.JsonNotFound{}				"Data not found"
 //		@Failure		500	{object}	entity.JsonInternalServerError{}	"Internal server error"
 //		@Router			/customers/batch [delete]
@@ -150,6 +320,10 @@ func (handler *CustomerHandler) DeleteBatch(ctx echo.Context) error {
 	err := ctx.Bind(request)
 	helper.ErrorPanic(err)
 
+	if partialMode(ctx) {
+		return handler.multiStatus(ctx, handler.customerUsecase.DeleteBatchPartial(c, *request))
+	}
+
 	handler.customerUsecase.DeleteBatch(c, *request)
 
 	webResponse := entity.Response{
@@ -162,6 +336,76 @@ func (handler *CustomerHandler) DeleteBatch(ctx echo.Context) error {
 	return ctx.JSON(http.StatusCreated, webResponse)
 }
 
+// Note             godoc
+//
+// @Summary		Restore batch customer
+// @Description	Restore soft deleted customers.
+// @Param		data	body	entity.RestoreBatchCustomerRequest	true	"restore batch customer"
+// @Produce		application/json
+// @Tags		customers
+// @Security		Bearer
+// @Success		200	{object}	entity.JsonSuccess{data=nil}		"Data"
+// @Failure		400	{object}	entity.JsonBadRequest{}				"Validation error, or an email now taken by another customer"
+// @Failure		401	{object}	entity.JsonUnauthorized{}			"Unauthorized"
+// @Failure		403	{object}	entity.JsonForbidden{}				"Forbidden"
+// @Failure		404	{object}	entity.JsonNotFound{}				"Data not found"
+// @Failure		500	{object}	entity.JsonInternalServerError{}	"Internal server error"
+// @Router		/customers/restore [post]
+func (handler *CustomerHandler) RestoreBatch(ctx echo.Context) error {
+	c, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	request := new(entity.RestoreBatchCustomerRequest)
+	err := ctx.Bind(request)
+	helper.ErrorPanic(err)
+
+	handler.customerUsecase.RestoreBatch(c, *request)
+
+	webResponse := entity.Response{
+		Code:    http.StatusOK,
+		Status:  "OK",
+		Message: "Restore Batch Successful",
+		Data:    nil,
+	}
+	utils.ResponseInterceptor(ctx, &webResponse)
+	return ctx.JSON(http.StatusOK, webResponse)
+}
+
+// Note             godoc
+//
+// @Summary		Purge batch customer
+// @Description	Permanently remove customers, only customers that were deleted before can be purged.
+// @Param		data	body	entity.PurgeBatchCustomerRequest	true	"purge batch customer"
+// @Produce		application/json
+// @Tags		customers
+// @Security		Bearer
+// @Success		200	{object}	entity.JsonSuccess{data=nil}		"Data"
+// @Failure		400	{object}	entity.JsonBadRequest{}				"Validation error"
+// @Failure		401	{object}	entity.JsonUnauthorized{}			"Unauthorized"
+// @Failure		403	{object}	entity.JsonForbidden{}				"Forbidden"
+// @Failure		404	{object}	entity.JsonNotFound{}				"Data not found"
+// @Failure		500	{object}	entity.JsonInternalServerError{}	"Internal server error"
+// @Router		/customers/purge [delete]
+func (handler *CustomerHandler) PurgeBatch(ctx echo.Context) error {
+	c, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	request := new(entity.PurgeBatchCustomerRequest)
+	err := ctx.Bind(request)
+	helper.ErrorPanic(err)
+
+	handler.customerUsecase.PurgeBatch(c, *request)
+
+	webResponse := entity.Response{
+		Code:    http.StatusOK,
+		Status:  "OK",
+		Message: "Purge Batch Successful",
+		Data:    nil,
+	}
+	utils.ResponseInterceptor(ctx, &webResponse)
+	return ctx.JSON(http.StatusOK, webResponse)
+}
+
 // Note 		    godoc
 //
 // @Summary		get customer by id.
@@ -169,8 +413,11 @@ func (handler *CustomerHandler) DeleteBatch(ctx echo.Context) error {
 // @Description	get customer by id.
 // @Produce		application/json
 // @Tags		customers
+// @Security		Bearer
 // @Success		200	{object}	entity.JsonSuccess{data=entity.CustomerResponse{}}	"Data"
 // @Failure		400	{object}	entity.JsonBadRequest{}								"Validation error"
+// @Failure		401	{object}	entity.JsonUnauthorized{}							"Unauthorized"
+// @Failure		403	{object}	entity.JsonForbidden{}							"Forbidden"
 // @Failure		404	{object}	entity.JsonNotFound{}								"Data not found"
 // @Failure		500	{object}	entity.JsonInternalServerError{}					"Internal server error"
 // @Router		/customers/{customerId} [get]
@@ -184,6 +431,7 @@ func (handler *CustomerHandler) FindById(ctx echo.Context) error {
 	}
 
 	data := handler.customerUsecase.FindById(c, *params)
+	ctx.Response().Header().Set("ETag", utils.ETag(data.Version))
 
 	webResponse := entity.Response{
 		Code:   http.StatusOK,
@@ -197,7 +445,9 @@ func (handler *CustomerHandler) FindById(ctx echo.Context) error {
 // Note             godoc
 //
 // @Summary		Get all customers.
-// @Description	Get all customers.
+// @Description	Get all customers. Any field can be filtered with filter[field][operator]=value,
+// @Description	operators are eq, neq, gt, gte, lt, lte, contains (case-insensitive substring), like and ilike (patterns with % and _),
+// @Description	in (a,b,c), between (a,b) and is_null (true/false), e.g. filter[email][contains]=gmail&filter[created_at][gte]=2024-01-01.
 // @Produce		application/json
 // @Param		limit		query	string	false	"limit"
 // @Param		page		query	string	false	"page"
@@ -206,9 +456,16 @@ func (handler *CustomerHandler) FindById(ctx echo.Context) error {
 // @Param		start_date	query	string	false	"start_date"
 // @Param		end_date	query	string	false	"end_date"
 // @Param		sort		query	string	false	"sort"
+// @Param		cursor		query	string	false	"next_cursor or prev_cursor from a previous page, switches to keyset pagination on the sort columns, NULLs sort as an empty string"
+// @Param		q			query	string	false	"search username, email, phone and address"
+// @Param		mode		query	string	false	"q match mode: contains (default), prefix, exact, fulltext (ranked), fuzzy (ranked, typo tolerant)"
+// @Param		include_deleted	query	bool	false	"also list soft deleted customers, requires customers:delete"
 // @Tags		customers
+// @Security		Bearer
 // @Success		200	{object}	entity.Response{data=[]entity.CustomerResponse{}}	"Data"
 // @Failure		400	{object}	entity.JsonBadRequest{}								"Validation error"
+// @Failure		401	{object}	entity.JsonUnauthorized{}							"Unauthorized"
+// @Failure		403	{object}	entity.JsonForbidden{}							"Forbidden"
 // @Failure		404	{object}	entity.JsonNotFound{}								"Data not found"
 // @Failure		500	{object}	entity.JsonInternalServerError{}					"Internal server error"
 // @Router		/customers [get]
@@ -221,6 +478,10 @@ func (handler *CustomerHandler) FindAllPaging(ctx echo.Context) error {
 	if err := ctx.Bind(&dataFilter); err != nil {
 		panic(exception.NewBadRequestHandler(err.Error()))
 	}
+	dataFilter.Filters = querybuilder.ParseFilterParams(ctx.QueryParams())
+	if dataFilter.IncludeDeleted && !middlewares.HasPermission(ctx, "customers:delete") {
+		panic(exception.NewForbiddenHandler("include_deleted requires permission customers:delete"))
+	}
 
 	response, paging := handler.customerUsecase.FindAllPaging(c, dataFilter)
 
@@ -236,21 +497,32 @@ func (handler *CustomerHandler) FindAllPaging(ctx echo.Context) error {
 
 //	    Note 		    godoc
 //
-//		@Summary		Export Excel customer.
-//		@Description	Export Excel customer.
-//		@Produce		application/json
+//		@Summary		Export customer.
+//		@Description	Export customer as xlsx, csv or jsonl. Takes the same filters as the customer list, including filter[field][operator]. The file is streamed as it is read from the database.
+//		@Produce		application/vnd.openxmlformats-officedocument.spreadsheetml.sheet,text/csv,application/x-ndjson
 //		@Tags			customers
+//		@Security		Bearer
 //		@Param			start_date	query		string	false	"start_date"
 //		@Param			end_date	query		string	false	"end_date"
 //		@Param			username	query		string	false	"username"
 //		@Param			email		query		string	false	"email"
-//		@Success		200			{object}	entity.JsonSuccess{data=string}"Data"
+//		@Param			sort		query		string	false	"sort"
+//		@Param			q			query		string	false	"search username, email, phone and address"
+//		@Param			mode		query		string	false	"q match mode: contains (default), prefix, exact, fulltext (ranked), fuzzy (ranked, typo tolerant)"
+//		@Param			include_deleted	query	bool	false	"also export soft deleted customers, requires customers:delete"
+//		@Param			format		query		string	false	"xlsx (default), csv or jsonl"
+//		@Param			delimiter	query		string	false	"csv field delimiter, a single character or tab, default ,"
+//		@Param			encoding	query		string	false	"csv encoding: utf-8 (default), utf-8-bom or latin1"
+//		@Success		200			{file}		file	"customer_<timestamp>.<format>"
 //		@Failure		400			{object}	entity.JsonBadRequest{}				"Validation error"
+//		@Failure		401			{object}	entity.JsonUnauthorized{}			"Unauthorized"
+//		@Failure		403			{object}	entity.JsonForbidden{}			"Forbidden"
 //		@Failure		404			{object}	entity.JsonNotFound{}				"Data not found"
 //		@Failure		500			{object}	entity.JsonInternalServerError{}	"Internal server error"
 //		@Router			/customers/export [get]
 func (handler *CustomerHandler) Export(ctx echo.Context) error {
-	c, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	// large exports stream for a while, so they get more time than a regular request
+	c, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
 	defer cancel()
 
 	var dataFilter entity.CustomerQueryFilter
@@ -258,34 +530,89 @@ func (handler *CustomerHandler) Export(ctx echo.Context) error {
 	if err := ctx.Bind(&dataFilter); err != nil {
 		panic(exception.NewBadRequestHandler(err.Error()))
 	}
+	dataFilter.Filters = querybuilder.ParseFilterParams(ctx.QueryParams())
+	if dataFilter.IncludeDeleted && !middlewares.HasPermission(ctx, "customers:delete") {
+		panic(exception.NewForbiddenHandler("include_deleted requires permission customers:delete"))
+	}
 
-	filePath, err := handler.customerUsecase.Export(c, dataFilter)
+	var fileFormat entity.FileFormatRequest
+	if err := ctx.Bind(&fileFormat); err != nil {
+		panic(exception.NewBadRequestHandler(err.Error()))
+	}
+	format, err := tabular.ParseFormat(fileFormat.Format)
+	helper.ErrorPanic(err)
+	options, err := tabular.ParseOptions(fileFormat.Delimiter, fileFormat.Encoding)
 	helper.ErrorPanic(err)
-	defer os.Remove(filePath) // Remove the file after the function exits
+	// a bad sort or filter has to be answered before the headers announce a file
+	helper.ErrorPanic(repo.CheckCustomerFilter(dataFilter))
 
-	fileName := filepath.Base(filePath)
-	// Set headers for the Excel file
-	ctx.Response().Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	fileName := fmt.Sprintf("customer_%s.%s", time.Now().Format("2006-01-02_150405"), format)
+	// Set headers for the file, the body is streamed straight into the response
+	ctx.Response().Header().Set("Content-Type", tabular.ContentType(format, options))
 	ctx.Response().Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", fileName))
 
-	// Read the Excel file and write to the response body
-	data, err := os.ReadFile(filePath)
-	helper.ErrorPanic(err)
+	if err := handler.customerUsecase.Export(c, dataFilter, format, options, ctx.Response()); err != nil {
+		if ctx.Response().Committed {
+			// csv and jsonl flush rows as they go, once the 200 is out the only way to tell the
+			// client the file is truncated is to drop the connection
+			ctx.Logger().Error("Handler : Export aborted mid-stream : ", err.Error())
+			panic(http.ErrAbortHandler)
+		}
+		ctx.Response().Header().Del("Content-Type")
+		ctx.Response().Header().Del("Content-Disposition")
+		panic(err)
+	}
 
-	// Write data to the response body
-	return ctx.Blob(http.StatusOK, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", data)
+	return nil
 }
 
 //	    Note 		    godoc
 //
-//		@Summary		Import Excel customer.
-//		@Description	Import Excel customer.
+//		@Summary		Download the customer import template.
+//		@Description	An xlsx with the MST_CUSTOMER sheet and its header ready to fill, cells check the import rules as they are typed. An Instructions sheet describes every column and shows example rows.
+//		@Produce		application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+//		@Tags			customers
+//		@Security		Bearer
+//		@Success		200			{file}		file	"customer_import_template.xlsx"
+//		@Failure		401			{object}	entity.JsonUnauthorized{}			"Unauthorized"
+//		@Failure		403			{object}	entity.JsonForbidden{}			"Forbidden"
+//		@Failure		500			{object}	entity.JsonInternalServerError{}	"Internal server error"
+//		@Router			/customers/import/template [get]
+func (handler *CustomerHandler) ImportTemplate(ctx echo.Context) error {
+	c, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	ctx.Response().Header().Set("Content-Type", tabular.ContentType(tabular.FormatXlsx, tabular.Options{}))
+	ctx.Response().Header().Set("Content-Disposition", "attachment; filename=customer_import_template.xlsx")
+
+	if err := handler.customerUsecase.ImportTemplate(c, ctx.Response()); err != nil {
+		ctx.Response().Header().Del("Content-Type")
+		ctx.Response().Header().Del("Content-Disposition")
+		panic(err)
+	}
+
+	return nil
+}
+
+//	    Note 		    godoc
+//
+//		@Summary		Import customer.
+//		@Description	Import customer from xlsx (sheet MST_CUSTOMER, or the first sheet), csv or jsonl. The format is detected from the file content. Columns are matched by header (username/name, email, phone, address, case-insensitive) in any order. With dry_run=true nothing is written and the data is an entity.ImportPreviewResponse.
 //		@Produce		application/json
 //		@Accept			multipart/form-data
 //		@Tags			customers
-//		@Param			file	formData	file	true	"Import Excel customer"
-//		@Success		200		{object}	entity.JsonSuccess{data=string}"Data"
+//		@Security		Bearer
+//		@Param			file		formData	file	true	"Import customer file"
+//		@Param			on_conflict	formData	string	false	"error (default), skip or update a customer whose email is taken"
+//		@Param			delimiter	formData	string	false	"csv field delimiter, a single character or tab, default ,"
+//		@Param			encoding	formData	string	false	"csv encoding: utf-8 (default, a BOM is skipped) or latin1"
+//		@Param			dry_run		query		bool	false	"Only validate the file and report what the import would do"
+//		@Param			preview		query		int		false	"Parsed rows returned by a dry run, 1 to 100, default 10"
+//		@Param			error_report	query	string	false	"json (default) or xlsx: a failed import answers with the file, its invalid cells highlighted and commented and an Import Errors sheet"
+//		@Success		200		{object}	entity.JsonSuccess{data=entity.BatchResult{}}"Data"
 //		@Failure		400		{object}	entity.JsonBadRequest{}				"Validation error"
+//		@Failure		401		{object}	entity.JsonUnauthorized{}			"Unauthorized"
+//		@Failure		403		{object}	entity.JsonForbidden{}			"Forbidden"
 //		@Failure		404		{object}	entity.JsonNotFound{}				"Data not found"
 //		@Failure		500		{object}	entity.JsonInternalServerError{}	"Internal server error"
 //		@Router			/customers/import [post]
@@ -300,22 +627,92 @@ func (handler *CustomerHandler) Import(ctx echo.Context) error {
 		panic(exception.NewBadRequestHandler(err.Error()))
 	}
 
-	fileExtension := filepath.Ext(file.Filename)
-	if fileExtension != ".xlsx" && fileExtension != ".xls" {
-		panic(exception.NewBadRequestHandler("Invalid file type. Only .xlsx and .xls are allowed"))
+	request.File = file
+	request.OnConflict = ctx.FormValue("on_conflict")
+	request.Delimiter = ctx.FormValue("delimiter")
+	request.Encoding = ctx.FormValue("encoding")
+
+	// Bind skips the query string on POST, dry_run and preview live there
+	if err := (&echo.DefaultBinder{}).BindQueryParams(ctx, request); err != nil {
+		panic(exception.NewBadRequestHandler(err.Error()))
 	}
 
-	request.File = file
+	if request.DryRun {
+		data, err := handler.customerUsecase.ImportPreview(c, *request)
+		helper.ErrorPanic(err)
+
+		webResponse := entity.Response{
+			Code:    http.StatusOK,
+			Status:  "Ok",
+			Message: "Import Dry Run",
+			Data:    data,
+		}
+		utils.ResponseInterceptor(ctx, &webResponse)
+		return ctx.JSON(http.StatusOK, webResponse)
+	}
+
+	// the annotated file comes out of the same pass that found the errors
+	var report bytes.Buffer
+	var reportWriter io.Writer
+	if request.ErrorReport == entity.ImportErrorReportXlsx {
+		reportWriter = &report
+	}
 
-	error := handler.customerUsecase.Import(c, *request)
+	data, error := handler.customerUsecase.Import(c, *request, reportWriter)
+	if _, invalid := error.(*exception.ExcelValidation); invalid && report.Len() > 0 {
+		return importErrorReport(ctx, *request, report.Bytes())
+	}
 	helper.ErrorPanic(error)
 
 	webResponse := entity.Response{
 		Code:    http.StatusOK,
 		Status:  "Ok",
 		Message: "Import Successful",
-		Data:    nil,
+		Data:    data,
 	}
 	utils.ResponseInterceptor(ctx, &webResponse)
 	return ctx.JSON(http.StatusOK, webResponse)
 }
+
+// importErrorReport answers a failed import with the file annotated with its errors, the status
+// stays 400 as for the json errors.
+func importErrorReport(ctx echo.Context, request entity.UploadCustomerRequest, report []byte) error {
+	name := strings.TrimSuffix(filepath.Base(request.File.Filename), filepath.Ext(request.File.Filename))
+	ctx.Response().Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name + "_errors.xlsx"}))
+	return ctx.Blob(http.StatusBadRequest, tabular.ContentType(tabular.FormatXlsx, tabular.Options{}), report)
+}
+
+// ifMatchVersion is the customer version the If-Match header of a write expects, utils.AnyETag
+// for "*".
+func ifMatchVersion(ctx echo.Context) int {
+	header := ctx.Request().Header.Get("If-Match")
+	if header == "" {
+		panic(exception.NewPreconditionRequiredHandler("If-Match header with the customer ETag is required"))
+	}
+
+	version, ok := utils.ParseETag(header)
+	if !ok {
+		if strings.HasPrefix(strings.TrimSpace(header), "W/") {
+			panic(exception.NewPreconditionFailedHandler("If-Match needs the strong ETag of the customer, weak ETags never match"))
+		}
+		panic(exception.NewBadRequestHandler(fmt.Sprintf("If-Match '%s' is not an ETag", header)))
+	}
+	return version
+}
+
+// partialMode reports whether the client asked for a batch to be processed item by item.
+func partialMode(ctx echo.Context) bool {
+	partial, _ := strconv.ParseBool(ctx.QueryParam("partial"))
+	return partial
+}
+
+// multiStatus answers a partial batch with 207 and the outcome of every item.
+func (handler *CustomerHandler) multiStatus(ctx echo.Context, results []entity.BatchItemResult) error {
+	webResponse := entity.Response{
+		Code:   http.StatusMultiStatus,
+		Status: "Multi-Status",
+		Data:   results,
+	}
+	utils.ResponseInterceptor(ctx, &webResponse)
+	return ctx.JSON(http.StatusMultiStatus, webResponse)
+}
@@ -0,0 +1,151 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"path/filepath"
+	"scylla/entity"
+	"scylla/pkg/exception"
+	"scylla/pkg/helper"
+	"scylla/pkg/middlewares"
+	"scylla/pkg/tabular"
+	"scylla/pkg/utils"
+	"scylla/usecase"
+	"time"
+)
+
+type ExportJobHandler struct {
+	exportJobUsecase usecase.ExportJobUsecase
+}
+
+func NewExportJobHandler(exportJobUsecase usecase.ExportJobUsecase) *ExportJobHandler {
+	return &ExportJobHandler{
+		exportJobUsecase: exportJobUsecase,
+	}
+}
+
+// Note            godoc
+//
+// @Summary		Create customer export job
+// @Description	Queue an export of the customers matching the filter, which takes the list filters as JSON. The file format and its options are query parameters, as for the synchronous export. Poll the job for progress and download the file once it is completed.
+// @Param		data		body	entity.CustomerQueryFilter	true	"customer filter"
+// @Param		format		query	string	false	"xlsx (default), csv or jsonl"
+// @Param		delimiter	query	string	false	"csv field delimiter, a single character or tab, default ,"
+// @Param		encoding	query	string	false	"csv encoding: utf-8 (default), utf-8-bom or latin1"
+// @Produce		application/json
+// @Tags		customers
+// @Security		Bearer
+// @Success		202	{object}	entity.JsonSuccess{data=entity.ExportJobResponse{}}	"Data"
+// @Failure		400	{object}	entity.JsonBadRequest{}								"Validation error"
+// @Failure		401	{object}	entity.JsonUnauthorized{}							"Unauthorized"
+// @Failure		403	{object}	entity.JsonForbidden{}								"Forbidden"
+// @Failure		500	{object}	entity.JsonInternalServerError{}					"Internal server error"
+// @Router		/customers/exports [post]
+func (handler *ExportJobHandler) Create(ctx echo.Context) error {
+	c, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var dataFilter entity.CustomerQueryFilter
+	if err := ctx.Bind(&dataFilter); err != nil {
+		panic(exception.NewBadRequestHandler(err.Error()))
+	}
+	if dataFilter.IncludeDeleted && !middlewares.HasPermission(ctx, "customers:delete") {
+		panic(exception.NewForbiddenHandler("include_deleted requires permission customers:delete"))
+	}
+
+	// a POST body is the filter, so the format is only read from the query string
+	var fileFormat entity.FileFormatRequest
+	if err := (&echo.DefaultBinder{}).BindQueryParams(ctx, &fileFormat); err != nil {
+		panic(exception.NewBadRequestHandler(err.Error()))
+	}
+	format, err := tabular.ParseFormat(fileFormat.Format)
+	helper.ErrorPanic(err)
+	options, err := tabular.ParseOptions(fileFormat.Delimiter, fileFormat.Encoding)
+	helper.ErrorPanic(err)
+
+	data := handler.exportJobUsecase.Create(c, currentUserId(ctx), dataFilter, format, options)
+
+	webResponse := entity.Response{
+		Code:    http.StatusAccepted,
+		Status:  "Accepted",
+		Message: "Export Queued",
+		Data:    data,
+	}
+	utils.ResponseInterceptor(ctx, &webResponse)
+	return ctx.JSON(http.StatusAccepted, webResponse)
+}
+
+// Note            godoc
+//
+// @Summary		Get customer export job
+// @Description	Get the state and progress of an export job.
+// @Param		exportId	path	string	true	"export_id"
+// @Produce		application/json
+// @Tags		customers
+// @Security		Bearer
+// @Success		200	{object}	entity.JsonSuccess{data=entity.ExportJobResponse{}}	"Data"
+// @Failure		400	{object}	entity.JsonBadRequest{}								"Validation error"
+// @Failure		401	{object}	entity.JsonUnauthorized{}							"Unauthorized"
+// @Failure		403	{object}	entity.JsonForbidden{}								"Forbidden"
+// @Failure		404	{object}	entity.JsonNotFound{}								"Data not found"
+// @Failure		500	{object}	entity.JsonInternalServerError{}					"Internal server error"
+// @Router		/customers/exports/{exportId} [get]
+func (handler *ExportJobHandler) FindById(ctx echo.Context) error {
+	c, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	params := new(entity.ExportJobParams)
+	if err := ctx.Bind(params); err != nil {
+		panic(exception.NewBadRequestHandler(err.Error()))
+	}
+
+	data := handler.exportJobUsecase.FindById(c, currentUserId(ctx), *params)
+
+	webResponse := entity.Response{
+		Code:   http.StatusOK,
+		Status: "OK",
+		Data:   data,
+	}
+	utils.ResponseInterceptor(ctx, &webResponse)
+	return ctx.JSON(http.StatusOK, webResponse)
+}
+
+// Note            godoc
+//
+// @Summary		Download customer export
+// @Description	Download the file of a completed export job, files are removed once the job expires. The file is kept by the instance that ran the job, which has to serve the download.
+// @Param		exportId	path	string	true	"export_id"
+// @Produce		application/vnd.openxmlformats-officedocument.spreadsheetml.sheet,text/csv,application/x-ndjson
+// @Tags		customers
+// @Security		Bearer
+// @Success		200	{file}		file								"customer export"
+// @Failure		400	{object}	entity.JsonBadRequest{}				"Export is not completed"
+// @Failure		401	{object}	entity.JsonUnauthorized{}			"Unauthorized"
+// @Failure		403	{object}	entity.JsonForbidden{}				"Forbidden"
+// @Failure		404	{object}	entity.JsonNotFound{}				"Data not found, expired or kept by another instance"
+// @Failure		500	{object}	entity.JsonInternalServerError{}	"Internal server error"
+// @Router		/customers/exports/{exportId}/download [get]
+func (handler *ExportJobHandler) Download(ctx echo.Context) error {
+	c, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	params := new(entity.ExportJobParams)
+	if err := ctx.Bind(params); err != nil {
+		panic(exception.NewBadRequestHandler(err.Error()))
+	}
+
+	filePath, contentType := handler.exportJobUsecase.Download(c, currentUserId(ctx), *params)
+
+	ctx.Response().Header().Set("Content-Type", contentType)
+	return ctx.Attachment(filePath, fmt.Sprintf("customer_export_%s%s", params.ExportId, filepath.Ext(filePath)))
+}
+
+func currentUserId(ctx echo.Context) int {
+	claims := middlewares.GetClaims(ctx)
+	if claims == nil {
+		panic(exception.NewUnauthorizedHandler("missing token"))
+	}
+	return claims.UserID
+}
@@ -1,15 +1,18 @@
 package main
 
 import (
+	"context"
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	echoSwagger "github.com/swaggo/echo-swagger"
+	"log"
 	"scylla/docs"
 	"scylla/handler"
 	"scylla/pkg/config"
 	"scylla/pkg/exception"
 	"scylla/pkg/middlewares"
+	"scylla/pkg/token"
 	"scylla/pkg/utils"
 	"scylla/repo"
 	"scylla/routes"
@@ -45,12 +48,38 @@ func main() {
 		docs.SwaggerInfo.Host = "localhost:3000"
 		docs.SwaggerInfo.BasePath = "/api/v1"
 	}
+	//jwt
+	tokenManager, err := token.NewManager(&loadConfig)
+	if err != nil {
+		panic(exception.NewInternalServerErrorHandler(err.Error()))
+	}
+
 	//init repo
+	userRepo := repo.NewUserRepoImpl(db)
+	refreshTokenRepo := repo.NewRefreshTokenRepoImpl(db)
 	customerRepo := repo.NewCustomerRepoImpl(db)
+	exportJobRepo := repo.NewExportJobRepoImpl(db)
 	//init usecase
+	authUsecase := usecase.NewAuthUsecaseImpl(userRepo, refreshTokenRepo, tokenManager, validate)
 	customerUsecase := usecase.NewCustomerUsecaseImpl(customerRepo, validate)
+	exportJobUsecase := usecase.NewExportJobUsecaseImpl(exportJobRepo, customerRepo, validate, loadConfig.ExportStorageDir, loadConfig.ExportTTL, loadConfig.ExportWorkers)
 	//init handler
+	authHandler := handler.NewAuthHandler(authUsecase)
 	customerHandler := handler.NewCustomerHandler(customerUsecase)
+	exportJobHandler := handler.NewExportJobHandler(exportJobUsecase)
+
+	//admin bootstrap, a fresh install has no user to log in with; LoadConfig checked the values
+	if loadConfig.AdminUsername != "" {
+		created := authUsecase.CreateUser(context.Background(), loadConfig.AdminUser())
+		if created {
+			log.Printf("admin bootstrap: created user %s", loadConfig.AdminUsername)
+		}
+	}
+
+	//export workers
+	if err := exportJobUsecase.Start(context.Background()); err != nil {
+		panic(exception.NewInternalServerErrorHandler(err.Error()))
+	}
 
 	//echo
 	app := echo.New()
@@ -68,15 +97,19 @@ func main() {
 		},
 	}))
 	app.Use(middleware.CORSWithConfig(middleware.CORSConfig{
-		AllowOrigins: []string{"*"},
-		AllowHeaders: []string{echo.HeaderOrigin, echo.HeaderContentType, echo.HeaderAccept, echo.HeaderAuthorization},
-		AllowMethods: []string{echo.GET, echo.POST, echo.PUT, echo.PATCH, echo.DELETE, echo.OPTIONS},
+		AllowOrigins:  []string{"*"},
+		AllowHeaders:  []string{echo.HeaderOrigin, echo.HeaderContentType, echo.HeaderAccept, echo.HeaderAuthorization, "If-Match"},
+		AllowMethods:  []string{echo.GET, echo.POST, echo.PUT, echo.PATCH, echo.DELETE, echo.OPTIONS},
+		ExposeHeaders: []string{"ETag"},
 	}))
 
 	//routes v1
 	routes.NewRoutesV1(
 		app,
+		middlewares.JwtMiddleware(tokenManager),
+		authHandler,
 		customerHandler,
+		exportJobHandler,
 	)
 
 	//docs swagger
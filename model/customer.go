@@ -1,15 +1,20 @@
 package model
 
-import "time"
+import (
+	"gorm.io/gorm"
+	"time"
+)
 
 type Customer struct {
-	ID        int       `json:"id" gorm:"type:int;primary_key"`
-	Username  string    `json:"username"`
-	Email     string    `json:"email"`
-	Phone     string    `json:"phone"`
-	Address   string    `json:"address"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID        int            `json:"id" gorm:"type:int;primary_key"`
+	Username  string         `json:"username"`
+	Email     string         `json:"email"`
+	Phone     string         `json:"phone"`
+	Address   string         `json:"address"`
+	Version   int            `json:"version" gorm:"default:1"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `json:"deleted_at"`
 }
 
 func (Customer) TableName() string {
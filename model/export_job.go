@@ -0,0 +1,37 @@
+package model
+
+import "time"
+
+// Export job states, a job moves queued -> running -> completed or failed, and ends as expired
+// once its file was cleaned up.
+const (
+	ExportJobQueued    = "queued"
+	ExportJobRunning   = "running"
+	ExportJobCompleted = "completed"
+	ExportJobFailed    = "failed"
+	ExportJobExpired   = "expired"
+)
+
+type ExportJob struct {
+	ID          string     `json:"id" gorm:"type:uuid;primary_key"`
+	UserID      int        `json:"user_id"`
+	State       string     `json:"state" gorm:"default:queued"`
+	Filter      string     `json:"filter" gorm:"type:jsonb"`
+	Format      string     `json:"format" gorm:"default:xlsx"`
+	Delimiter   string     `json:"delimiter"`
+	Encoding    string     `json:"encoding"`
+	TotalRows   int        `json:"total_rows"`
+	RowsWritten int        `json:"rows_written"`
+	FilePath    string     `json:"-"`
+	Instance    string     `json:"-"`
+	Error       string     `json:"error"`
+	HeartbeatAt *time.Time `json:"-"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	FinishedAt  *time.Time `json:"finished_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+func (ExportJob) TableName() string {
+	return "export_jobs"
+}
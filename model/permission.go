@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+type Permission struct {
+	ID          int       `json:"id" gorm:"type:int;primary_key"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func (Permission) TableName() string {
+	return "permissions"
+}
@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+type RefreshToken struct {
+	ID         int        `json:"id" gorm:"type:int;primary_key"`
+	UserID     int        `json:"user_id"`
+	TokenHash  string     `json:"-"`
+	FamilyID   string     `json:"family_id"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	ReplacedBy *int       `json:"replaced_by"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func (RefreshToken) TableName() string {
+	return "refresh_tokens"
+}
@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+type Role struct {
+	ID          int          `json:"id" gorm:"type:int;primary_key"`
+	Name        string       `json:"name"`
+	Permissions []Permission `json:"permissions" gorm:"many2many:role_permissions"`
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+}
+
+func (Role) TableName() string {
+	return "roles"
+}
@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+type User struct {
+	ID        int       `json:"id" gorm:"type:int;primary_key"`
+	Username  string    `json:"username"`
+	Email     string    `json:"email"`
+	Password  string    `json:"-"`
+	RoleID    *int      `json:"role_id"`
+	Role      *Role     `json:"role,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (User) TableName() string {
+	return "users"
+}
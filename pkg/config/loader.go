@@ -1,20 +1,41 @@
 package config
 
 import (
+	"errors"
+	"github.com/go-playground/validator/v10"
 	"github.com/spf13/viper"
+	"scylla/entity"
+	"scylla/pkg/exception"
+	"scylla/pkg/utils"
+	"sort"
+	"strings"
+	"time"
 )
 
 type Config struct {
-	DBHost      string `mapstructure:"POSTGRES_HOST"`
-	DBUsername  string `mapstructure:"POSTGRES_USER"`
-	DBPassword  string `mapstructure:"POSTGRES_PASSWORD"`
-	DBName      string `mapstructure:"POSTGRES_DB"`
-	DBPort      string `mapstructure:"POSTGRES_PORT"`
-	KongUrl     string `mapstructure:"KONG_URL"`
-	SwaggerHost string `mapstructure:"SWAGGER_HOST"`
-	SwaggerUrl  string `mapstructure:"SWAGGER_URL"`
-	Environment string `mapstructure:"ENVIRONMENT"`
-	ServerPort  string `mapstructure:"PORT"`
+	DBHost             string        `mapstructure:"POSTGRES_HOST"`
+	DBUsername         string        `mapstructure:"POSTGRES_USER"`
+	DBPassword         string        `mapstructure:"POSTGRES_PASSWORD"`
+	DBName             string        `mapstructure:"POSTGRES_DB"`
+	DBPort             string        `mapstructure:"POSTGRES_PORT"`
+	KongUrl            string        `mapstructure:"KONG_URL"`
+	SwaggerHost        string        `mapstructure:"SWAGGER_HOST"`
+	SwaggerUrl         string        `mapstructure:"SWAGGER_URL"`
+	Environment        string        `mapstructure:"ENVIRONMENT"`
+	ServerPort         string        `mapstructure:"PORT"`
+	JwtSecretKey       string        `mapstructure:"JWT_SECRET_KEY"`
+	JwtAlgorithm       string        `mapstructure:"JWT_ALGORITHM"`
+	JwtPrivateKeyPath  string        `mapstructure:"JWT_PRIVATE_KEY_PATH"`
+	JwtPublicKeyPath   string        `mapstructure:"JWT_PUBLIC_KEY_PATH"`
+	JwtIssuer          string        `mapstructure:"JWT_ISSUER"`
+	JwtAccessTokenTTL  time.Duration `mapstructure:"JWT_ACCESS_TOKEN_TTL"`
+	JwtRefreshTokenTTL time.Duration `mapstructure:"JWT_REFRESH_TOKEN_TTL"`
+	ExportStorageDir   string        `mapstructure:"EXPORT_STORAGE_DIR"`
+	ExportTTL          time.Duration `mapstructure:"EXPORT_TTL"`
+	ExportWorkers      int           `mapstructure:"EXPORT_WORKERS"`
+	AdminUsername      string        `mapstructure:"ADMIN_USERNAME"`
+	AdminEmail         string        `mapstructure:"ADMIN_EMAIL"`
+	AdminPassword      string        `mapstructure:"ADMIN_PASSWORD"`
 }
 
 func LoadConfig(path string) (config Config, err error) {
@@ -24,6 +45,17 @@ func LoadConfig(path string) (config Config, err error) {
 	viper.AddConfigPath(".")
 	viper.SetConfigFile(".env")
 
+	viper.SetDefault("JWT_ALGORITHM", "HS256")
+	viper.SetDefault("JWT_ACCESS_TOKEN_TTL", "15m")
+	viper.SetDefault("JWT_REFRESH_TOKEN_TTL", "720h")
+	viper.SetDefault("EXPORT_STORAGE_DIR", "storage/exports")
+	viper.SetDefault("EXPORT_TTL", "24h")
+	viper.SetDefault("EXPORT_WORKERS", 2)
+	// registered so the environment alone can set them, they have no sensible default
+	viper.SetDefault("ADMIN_USERNAME", "")
+	viper.SetDefault("ADMIN_EMAIL", "")
+	viper.SetDefault("ADMIN_PASSWORD", "")
+
 	viper.AutomaticEnv()
 
 	err = viper.ReadInConfig()
@@ -32,5 +64,50 @@ func LoadConfig(path string) (config Config, err error) {
 	}
 
 	err = viper.Unmarshal(&config)
+	if err != nil {
+		return
+	}
+
+	err = config.validateAdmin()
 	return
 }
+
+// AdminUser is the admin the bootstrap creates on startup.
+func (config Config) AdminUser() entity.CreateUserRequest {
+	return entity.CreateUserRequest{
+		Username: config.AdminUsername,
+		Email:    config.AdminEmail,
+		Password: config.AdminPassword,
+		Role:     "admin",
+	}
+}
+
+// adminSettings names the setting behind every field of CreateUserRequest.
+var adminSettings = map[string]string{
+	"username": "ADMIN_USERNAME",
+	"email":    "ADMIN_EMAIL",
+	"password": "ADMIN_PASSWORD",
+}
+
+// validateAdmin runs the admin bootstrap settings through the validator CreateUser uses, so a bad
+// value stops startup with the setting's name instead of a validator panic.
+func (config Config) validateAdmin() error {
+	if config.AdminUsername == "" && config.AdminPassword == "" && config.AdminEmail == "" {
+		return nil
+	}
+	if config.AdminUsername == "" || config.AdminPassword == "" {
+		return errors.New("ADMIN_USERNAME and ADMIN_PASSWORD must be set together")
+	}
+
+	err := utils.InitializeValidator(nil).Struct(config.AdminUser())
+	validationErrors, ok := err.(validator.ValidationErrors)
+	if !ok {
+		return err
+	}
+	var messages []string
+	for field, message := range exception.ValidationReport(validationErrors) {
+		messages = append(messages, adminSettings[field]+": "+message)
+	}
+	sort.Strings(messages)
+	return errors.New(strings.Join(messages, ", "))
+}
@@ -0,0 +1,42 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateAdmin(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  Config
+		message string
+	}{
+		{"no bootstrap", Config{}, ""},
+		{"valid", Config{AdminUsername: "admin", AdminEmail: "admin@example.com", AdminPassword: "s3cret-pass"}, ""},
+		{"without email", Config{AdminUsername: "admin", AdminPassword: "s3cret-pass"}, ""},
+		{"password only", Config{AdminPassword: "s3cret-pass"}, "ADMIN_USERNAME and ADMIN_PASSWORD"},
+		{"username only", Config{AdminUsername: "admin"}, "ADMIN_USERNAME and ADMIN_PASSWORD"},
+		{"email only", Config{AdminEmail: "admin@example.com"}, "ADMIN_USERNAME and ADMIN_PASSWORD"},
+		{"long username", Config{AdminUsername: strings.Repeat("a", 126), AdminPassword: "s3cret-pass"}, "ADMIN_USERNAME"},
+		{"short password", Config{AdminUsername: "admin", AdminPassword: "short"}, "ADMIN_PASSWORD"},
+		{"multibyte short password", Config{AdminUsername: "admin", AdminPassword: "ééééé"}, "ADMIN_PASSWORD"},
+		{"72 byte password", Config{AdminUsername: "admin", AdminPassword: strings.Repeat("é", 36)}, ""},
+		{"long password", Config{AdminUsername: "admin", AdminPassword: strings.Repeat("é", 37)}, "ADMIN_PASSWORD"},
+		{"bad email", Config{AdminUsername: "admin", AdminEmail: "admin", AdminPassword: "s3cret-pass"}, "ADMIN_EMAIL"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.config.validateAdmin()
+			if test.message == "" {
+				if err != nil {
+					t.Errorf("validateAdmin() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.message) {
+				t.Errorf("validateAdmin() error = %v, want it to mention %s", err, test.message)
+			}
+		})
+	}
+}
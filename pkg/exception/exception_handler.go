@@ -25,6 +25,10 @@ func ExceptionHandlers(err error, ctx echo.Context) {
 		return
 	} else if forbiddenError(err, ctx) {
 		return
+	} else if preconditionFailedError(err, ctx) {
+		return
+	} else if preconditionRequiredError(err, ctx) {
+		return
 	} else {
 		internalServerError(err, ctx)
 		return
@@ -34,61 +38,10 @@ func ExceptionHandlers(err error, ctx echo.Context) {
 func validationError(err error, ctx echo.Context) bool {
 
 	if castedObject, ok := err.(validator.ValidationErrors); ok {
-		report := make(map[string]string)
-		var fieldName string
-
-		for _, e := range castedObject {
-			if len(e.Namespace()) > 0 && unicode.IsUpper(rune(e.Namespace()[0])) {
-				dotIndex := strings.Index(e.Namespace(), ".")
-				if dotIndex != -1 {
-					fieldName = e.Namespace()[dotIndex+1:]
-				}
-			} else {
-				fieldName = e.Field()
-			}
-			switch e.Tag() {
-			case "required":
-				report[fieldName] = fmt.Sprintf("%s is required", fieldName)
-			case "email":
-				report[fieldName] = fmt.Sprintf("%s is not valid email", fieldName)
-			case "gte":
-				report[fieldName] = fmt.Sprintf("%s value must be greater than %s", fieldName, e.Param())
-			case "lte":
-				report[fieldName] = fmt.Sprintf("%s value must be lower than %s", fieldName, e.Param())
-			case "unique":
-				report[fieldName] = fmt.Sprintf("%s has already been taken %s", fieldName)
-			case "max":
-				report[fieldName] = fmt.Sprintf("%s value must be lower than %s", fieldName, e.Param())
-			case "min":
-				report[fieldName] = fmt.Sprintf("%s value must be greater than %s", fieldName, e.Param())
-			case "numeric":
-				report[fieldName] = fmt.Sprintf("%s value must be numeric", fieldName)
-			case "number":
-				report[fieldName] = fmt.Sprintf("%s value must be number", fieldName)
-			case "oneof":
-				report[fieldName] = fmt.Sprintf("%s value must be %s", fieldName, e.Param())
-			case "len":
-				report[fieldName] = fmt.Sprintf("%s value must be exactly %s characters long", fieldName, e.Param())
-			case "alphanum":
-				report[fieldName] = fmt.Sprintf("%s value must be char and numeric", fieldName, e.Param())
-			case "notEmptyStringSlice":
-				report[fieldName] = fmt.Sprintf("%s value ​​in the array cannot be empty is string", fieldName)
-			case "dive":
-				report[fieldName] = fmt.Sprintf("%s value ​​in the array cannot be empty", fieldName)
-			case "date":
-				report[fieldName] = fmt.Sprintf("%s value must be date (yyyy-mm-dd)", fieldName)
-			case "notEmptyIntSlice":
-				report[fieldName] = fmt.Sprintf("%s value ​​in the array cannot be empty is int", fieldName)
-			case "isInt":
-				report[fieldName] = fmt.Sprintf("%s value must be of type int", fieldName)
-			case "isString":
-				report[fieldName] = fmt.Sprintf("%s value must be of type string", fieldName)
-			}
-		}
 		webResponse := entity.Error{
 			Code:   http.StatusBadRequest,
 			Status: "BAD REQUEST",
-			Errors: report,
+			Errors: ValidationReport(castedObject),
 		}
 		utils.ErrorInterceptor(ctx, &webResponse)
 		ctx.JSON(http.StatusBadRequest, webResponse)
@@ -97,6 +50,66 @@ func validationError(err error, ctx echo.Context) bool {
 	return false
 }
 
+// ValidationReport turns validator errors into the field -> message map every validation error
+// response carries.
+func ValidationReport(errs validator.ValidationErrors) map[string]string {
+	report := make(map[string]string)
+	var fieldName string
+
+	for _, e := range errs {
+		if len(e.Namespace()) > 0 && unicode.IsUpper(rune(e.Namespace()[0])) {
+			dotIndex := strings.Index(e.Namespace(), ".")
+			if dotIndex != -1 {
+				fieldName = e.Namespace()[dotIndex+1:]
+			}
+		} else {
+			fieldName = e.Field()
+		}
+		switch e.Tag() {
+		case "required":
+			report[fieldName] = fmt.Sprintf("%s is required", fieldName)
+		case "email":
+			report[fieldName] = fmt.Sprintf("%s is not valid email", fieldName)
+		case "gte":
+			report[fieldName] = fmt.Sprintf("%s value must be greater than %s", fieldName, e.Param())
+		case "lte":
+			report[fieldName] = fmt.Sprintf("%s value must be lower than %s", fieldName, e.Param())
+		case "unique":
+			report[fieldName] = fmt.Sprintf("%s has already been taken", fieldName)
+		case "max":
+			report[fieldName] = fmt.Sprintf("%s value must be lower than %s", fieldName, e.Param())
+		case "min":
+			report[fieldName] = fmt.Sprintf("%s value must be greater than %s", fieldName, e.Param())
+		case "maxBytes":
+			report[fieldName] = fmt.Sprintf("%s value must be at most %s bytes", fieldName, e.Param())
+		case "numeric":
+			report[fieldName] = fmt.Sprintf("%s value must be numeric", fieldName)
+		case "number":
+			report[fieldName] = fmt.Sprintf("%s value must be number", fieldName)
+		case "oneof":
+			report[fieldName] = fmt.Sprintf("%s value must be %s", fieldName, e.Param())
+		case "len":
+			report[fieldName] = fmt.Sprintf("%s value must be exactly %s characters long", fieldName, e.Param())
+		case "alphanum":
+			report[fieldName] = fmt.Sprintf("%s value must be char and numeric", fieldName)
+		case "notEmptyStringSlice":
+			report[fieldName] = fmt.Sprintf("%s value ​​in the array cannot be empty is string", fieldName)
+		case "dive":
+			report[fieldName] = fmt.Sprintf("%s value ​​in the array cannot be empty", fieldName)
+		case "date":
+			report[fieldName] = fmt.Sprintf("%s value must be date (yyyy-mm-dd)", fieldName)
+		case "notEmptyIntSlice":
+			report[fieldName] = fmt.Sprintf("%s value ​​in the array cannot be empty is int", fieldName)
+		case "isInt":
+			report[fieldName] = fmt.Sprintf("%s value must be of type int", fieldName)
+		case "isString":
+			report[fieldName] = fmt.Sprintf("%s value must be of type string", fieldName)
+		}
+	}
+
+	return report
+}
+
 func excelValidation(err error, ctx echo.Context) bool {
 	exception, ok := err.(*ExcelValidation)
 	if ok {
@@ -172,6 +185,36 @@ func forbiddenError(err error, ctx echo.Context) bool {
 	return false
 }
 
+func preconditionFailedError(err error, ctx echo.Context) bool {
+	exception, ok := err.(*PreconditionFailedStruct)
+	if ok {
+		webResponse := entity.Error{
+			Code:   http.StatusPreconditionFailed,
+			Status: "PRECONDITION FAILED",
+			Errors: exception.Error(),
+		}
+		utils.ErrorInterceptor(ctx, &webResponse)
+		ctx.JSON(http.StatusPreconditionFailed, webResponse)
+		return true
+	}
+	return false
+}
+
+func preconditionRequiredError(err error, ctx echo.Context) bool {
+	exception, ok := err.(*PreconditionRequiredStruct)
+	if ok {
+		webResponse := entity.Error{
+			Code:   http.StatusPreconditionRequired,
+			Status: "PRECONDITION REQUIRED",
+			Errors: exception.Error(),
+		}
+		utils.ErrorInterceptor(ctx, &webResponse)
+		ctx.JSON(http.StatusPreconditionRequired, webResponse)
+		return true
+	}
+	return false
+}
+
 func internalServerError(err error, ctx echo.Context) bool {
 	exception, ok := err.(*InternalServerErrorStruct)
 	if ok {
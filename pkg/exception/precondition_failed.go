@@ -0,0 +1,15 @@
+package exception
+
+type PreconditionFailedStruct struct {
+	ErrorMsg string
+}
+
+func NewPreconditionFailedHandler(msg string) *PreconditionFailedStruct {
+	return &PreconditionFailedStruct{
+		ErrorMsg: msg,
+	}
+}
+
+func (e *PreconditionFailedStruct) Error() string {
+	return e.ErrorMsg
+}
@@ -0,0 +1,15 @@
+package exception
+
+type PreconditionRequiredStruct struct {
+	ErrorMsg string
+}
+
+func NewPreconditionRequiredHandler(msg string) *PreconditionRequiredStruct {
+	return &PreconditionRequiredStruct{
+		ErrorMsg: msg,
+	}
+}
+
+func (e *PreconditionRequiredStruct) Error() string {
+	return e.ErrorMsg
+}
@@ -0,0 +1,28 @@
+package helper
+
+import (
+	"encoding/base64"
+	"encoding/json"
+)
+
+// Cursor is the decoded form of the opaque next_cursor/prev_cursor strings.
+// Values holds the sort key of the boundary row, Sort the sort spec it was built for.
+type Cursor struct {
+	Values   []interface{} `json:"v"`
+	Sort     string        `json:"s,omitempty"`
+	Backward bool          `json:"b,omitempty"`
+}
+
+func EncodeCursor(cursor Cursor) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(StructToJson(cursor)))
+}
+
+func DecodeCursor(raw string) (cursor Cursor, err error) {
+	bytes, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return cursor, err
+	}
+
+	err = json.Unmarshal(bytes, &cursor)
+	return cursor, err
+}
@@ -1,7 +0,0 @@
-package helper
-
-var RulesExcelCustomer = map[int]string{
-	0: "username,required",
-	1: "email,required,unique",
-	2: "password,required",
-}
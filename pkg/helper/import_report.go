@@ -0,0 +1,132 @@
+package helper
+
+import (
+	"fmt"
+	"github.com/xuri/excelize/v2"
+	"strings"
+)
+
+// ImportErrorSheet is the sheet AnnotateImport lists the errors on.
+const ImportErrorSheet = "Import Errors"
+
+// ImportError is one error of an import file. Row counts from 1 like the sheet, Column from 0 and
+// is -1 when the error is about no cell in particular.
+type ImportError struct {
+	Row     int
+	Column  int
+	Field   string
+	Message string
+}
+
+// AnnotateImport turns file into an error report: every cell of sheet holding an error gets a red
+// fill and a comment with its messages, and an ImportErrorSheet lists all the errors. The cells
+// keep the rest of their style, so the report still looks like the file that was sent.
+func AnnotateImport(file *excelize.File, sheet string, errors []ImportError) error {
+	var cells []string
+	messages := map[string][]string{}
+	for _, importError := range errors {
+		if importError.Column < 0 {
+			continue
+		}
+		cell, err := excelize.CoordinatesToCellName(importError.Column+1, importError.Row)
+		if err != nil {
+			return err
+		}
+		if _, ok := messages[cell]; !ok {
+			cells = append(cells, cell)
+		}
+		messages[cell] = append(messages[cell], importError.Message)
+	}
+
+	for _, cell := range cells {
+		if err := highlightCell(file, sheet, cell); err != nil {
+			return err
+		}
+
+		// a report sent back after a partial fix still carries the previous comments
+		if err := file.DeleteComment(sheet, cell); err != nil {
+			return err
+		}
+		err := file.AddComment(sheet, excelize.Comment{
+			Author: "Import",
+			Cell:   cell,
+			Text:   strings.Join(messages[cell], "\n"),
+			Width:  300,
+			Height: uint(40 + 20*len(messages[cell])),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return errorSheet(file, errors)
+}
+
+func highlightCell(file *excelize.File, sheet string, cell string) error {
+	styleId, err := file.GetCellStyle(sheet, cell)
+	if err != nil {
+		return err
+	}
+	style, err := file.GetStyle(styleId)
+	if err != nil {
+		return err
+	}
+
+	style.Fill = excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"FFC7CE"}}
+	if style.Font == nil {
+		style.Font = &excelize.Font{}
+	}
+	style.Font.Color = "9C0006"
+	highlighted, err := file.NewStyle(style)
+	if err != nil {
+		return err
+	}
+	return file.SetCellStyle(sheet, cell, cell, highlighted)
+}
+
+func errorSheet(file *excelize.File, errors []ImportError) error {
+	if index, _ := file.GetSheetIndex(ImportErrorSheet); index != -1 {
+		if err := file.DeleteSheet(ImportErrorSheet); err != nil {
+			return err
+		}
+	}
+	if _, err := file.NewSheet(ImportErrorSheet); err != nil {
+		return err
+	}
+
+	headerStyle, err := file.NewStyle(&excelize.Style{
+		Font: &excelize.Font{Bold: true, Color: "FFFFFF"},
+		Fill: excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"9C0006"}},
+	})
+	if err != nil {
+		return err
+	}
+
+	rows := [][]interface{}{
+		{fmt.Sprintf("%d errors, the cells holding them are highlighted and commented", len(errors))},
+		{"Row", "Cell", "Field", "Error"},
+	}
+	for _, importError := range errors {
+		cell := ""
+		if importError.Column >= 0 {
+			cell, err = excelize.CoordinatesToCellName(importError.Column+1, importError.Row)
+			if err != nil {
+				return err
+			}
+		}
+		rows = append(rows, []interface{}{importError.Row, cell, importError.Field, importError.Message})
+	}
+
+	for i, row := range rows {
+		if err := file.SetSheetRow(ImportErrorSheet, fmt.Sprintf("A%d", i+1), &row); err != nil {
+			return err
+		}
+	}
+	if err := file.SetCellStyle(ImportErrorSheet, "A2", "D2", headerStyle); err != nil {
+		return err
+	}
+	if err := file.SetColWidth(ImportErrorSheet, "C", "C", 20); err != nil {
+		return err
+	}
+	return file.SetColWidth(ImportErrorSheet, "D", "D", 80)
+}
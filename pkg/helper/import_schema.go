@@ -0,0 +1,124 @@
+package helper
+
+import (
+	"reflect"
+	"strings"
+)
+
+// ImportColumn binds a column of an import file to a field of the struct its rows decode into.
+// Field is the field's json name, Aliases the headers the column is found by, the first one being
+// the header templates use.
+type ImportColumn struct {
+	Field       string
+	Aliases     []string
+	Description string
+	// Text formats the column as text in templates, so Excel keeps leading zeros
+	Text bool
+}
+
+// ImportSchema decodes the rows of an import file into Target's struct type, so they go through
+// the same validate tags as the API. Which columns are required and which must be unique across
+// the file is read from those tags too.
+type ImportSchema struct {
+	Target  interface{}
+	Columns []ImportColumn
+	// Ignored are headers accepted in a file but never read
+	Ignored []string
+	// Examples are rows in Columns order shown by the template
+	Examples [][]string
+}
+
+// Header maps a header row to the column index of every field found in it. Headers match an alias
+// ignoring case, surrounding spaces and the difference between "_", "-" and " ". Required fields
+// that are not found are listed in missing, headers matching no column in unknown and headers
+// matching a column already found in duplicate, both by position. Blank headers are skipped.
+func (schema ImportSchema) Header(header []string) (index map[string]int, missing []string, unknown []int, duplicate []int) {
+	aliases := map[string]string{}
+	for _, column := range schema.Columns {
+		for _, alias := range column.Aliases {
+			aliases[normalizeHeader(alias)] = column.Field
+		}
+	}
+	for _, alias := range schema.Ignored {
+		aliases[normalizeHeader(alias)] = ""
+	}
+
+	index = map[string]int{}
+	for i, title := range header {
+		name := normalizeHeader(title)
+		if name == "" {
+			continue
+		}
+
+		field, ok := aliases[name]
+		if !ok {
+			unknown = append(unknown, i)
+			continue
+		}
+		if field == "" {
+			continue
+		}
+		if _, found := index[field]; found {
+			duplicate = append(duplicate, i)
+			continue
+		}
+		index[field] = i
+	}
+
+	for _, column := range schema.Columns {
+		if _, ok := index[column.Field]; !ok && schema.HasRule(column.Field, "required") {
+			missing = append(missing, column.Field)
+		}
+	}
+
+	return index, missing, unknown, duplicate
+}
+
+// Cell returns the trimmed cell of field in row, "" when the column is absent or the row is short.
+func (schema ImportSchema) Cell(row []string, index map[string]int, field string) string {
+	i, ok := index[field]
+	if !ok || i >= len(row) {
+		return ""
+	}
+	return strings.TrimSpace(row[i])
+}
+
+// Decode fills the string fields of dest, a pointer to a Target, from row.
+func (schema ImportSchema) Decode(row []string, index map[string]int, dest interface{}) {
+	value := reflect.ValueOf(dest).Elem()
+	names := JsonFieldNames(dest)
+	for _, column := range schema.Columns {
+		field := value.FieldByName(names[column.Field])
+		if field.IsValid() && field.Kind() == reflect.String {
+			field.SetString(schema.Cell(row, index, column.Field))
+		}
+	}
+}
+
+// HasRule tells the validate tag of field holds rule, "unique" matching "unique=customers;email".
+func (schema ImportSchema) HasRule(field string, rule string) bool {
+	_, ok := schema.Rule(field, rule)
+	return ok
+}
+
+// Rule returns the parameter of rule in the validate tag of field, "125" for "max=125".
+func (schema ImportSchema) Rule(field string, rule string) (param string, ok bool) {
+	objType := reflect.Indirect(reflect.ValueOf(schema.Target)).Type()
+	structField, found := objType.FieldByName(JsonFieldNames(schema.Target)[field])
+	if !found {
+		return "", false
+	}
+
+	for _, tag := range strings.Split(structField.Tag.Get("validate"), ",") {
+		name, param, _ := strings.Cut(tag, "=")
+		if name == rule {
+			return param, true
+		}
+	}
+	return "", false
+}
+
+func normalizeHeader(title string) string {
+	title = strings.NewReplacer("_", " ", "-", " ").Replace(strings.ToLower(title))
+	return strings.Join(strings.Fields(title), " ")
+}
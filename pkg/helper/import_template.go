@@ -0,0 +1,226 @@
+package helper
+
+import (
+	"fmt"
+	"github.com/xuri/excelize/v2"
+	"strconv"
+	"strings"
+)
+
+// Template builds an empty import workbook: sheet with the styled header of every column and
+// data validations derived from the validate tags, then an Instructions sheet describing the
+// columns and showing the example rows.
+func (schema ImportSchema) Template(sheet string) (*excelize.File, error) {
+	file := excelize.NewFile()
+	if err := file.SetSheetName("Sheet1", sheet); err != nil {
+		return nil, err
+	}
+
+	headerStyle, err := file.NewStyle(&excelize.Style{
+		Font:      &excelize.Font{Bold: true, Color: "FFFFFF"},
+		Fill:      excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"1F4E78"}},
+		Alignment: &excelize.Alignment{Horizontal: "center", Vertical: "center"},
+		Border: []excelize.Border{
+			{Type: "left", Color: "FFFFFF", Style: 1},
+			{Type: "right", Color: "FFFFFF", Style: 1},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	textStyle, err := file.NewStyle(&excelize.Style{NumFmt: 49})
+	if err != nil {
+		return nil, err
+	}
+
+	for i, column := range schema.Columns {
+		name, err := excelize.ColumnNumberToName(i + 1)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := file.SetCellValue(sheet, name+"1", column.Aliases[0]); err != nil {
+			return nil, err
+		}
+		if err := file.SetColWidth(sheet, name, name, 30); err != nil {
+			return nil, err
+		}
+		if column.Text {
+			// the header gets its own style below, the format applies to the data cells
+			if err := file.SetColStyle(sheet, name, textStyle); err != nil {
+				return nil, err
+			}
+		}
+
+		validation, err := schema.dataValidation(column, fmt.Sprintf("%s2:%s%d", name, name, excelize.TotalRows))
+		if err != nil {
+			return nil, err
+		}
+		if err := file.AddDataValidation(sheet, validation); err != nil {
+			return nil, err
+		}
+	}
+
+	last, err := excelize.ColumnNumberToName(len(schema.Columns))
+	if err != nil {
+		return nil, err
+	}
+	if err := file.SetCellStyle(sheet, "A1", last+"1", headerStyle); err != nil {
+		return nil, err
+	}
+	// keep the header in sight while scrolling through the rows
+	if err := file.SetPanes(sheet, &excelize.Panes{Freeze: true, YSplit: 1, TopLeftCell: "A2", ActivePane: "bottomLeft"}); err != nil {
+		return nil, err
+	}
+
+	if err := schema.instructions(file, sheet, headerStyle); err != nil {
+		return nil, err
+	}
+
+	return file, nil
+}
+
+// dataValidation turns the rules of column into an Excel validation on sqref: oneof becomes a
+// drop down, len/min/max a text length limit. The input message lists every rule.
+func (schema ImportSchema) dataValidation(column ImportColumn, sqref string) (*excelize.DataValidation, error) {
+	required := schema.HasRule(column.Field, "required")
+	validation := excelize.NewDataValidation(!required)
+	validation.SetSqref(sqref)
+	validation.SetInput(column.Aliases[0], strings.Join(schema.describe(column.Field), "\n"))
+
+	if values, ok := schema.Rule(column.Field, "oneof"); ok {
+		if err := validation.SetDropList(strings.Fields(values)); err != nil {
+			return nil, err
+		}
+		validation.SetError(excelize.DataValidationErrorStyleStop, column.Aliases[0], "Pick a value from the list")
+		return validation, nil
+	}
+
+	if length, ok := schema.Rule(column.Field, "len"); ok {
+		size, err := strconv.Atoi(length)
+		if err != nil {
+			return nil, err
+		}
+		if err := validation.SetRange(size, size, excelize.DataValidationTypeTextLength, excelize.DataValidationOperatorEqual); err != nil {
+			return nil, err
+		}
+		validation.SetError(excelize.DataValidationErrorStyleStop, column.Aliases[0], fmt.Sprintf("Must be exactly %d characters long", size))
+		return validation, nil
+	}
+
+	minimum, maximum := 0, 0
+	if required {
+		minimum = 1
+	}
+	if param, ok := schema.Rule(column.Field, "min"); ok {
+		if size, err := strconv.Atoi(param); err == nil {
+			minimum = size
+		}
+	}
+	if param, ok := schema.Rule(column.Field, "max"); ok {
+		if size, err := strconv.Atoi(param); err == nil {
+			maximum = size
+		}
+	}
+	if maximum > 0 {
+		if err := validation.SetRange(minimum, maximum, excelize.DataValidationTypeTextLength, excelize.DataValidationOperatorBetween); err != nil {
+			return nil, err
+		}
+		validation.SetError(excelize.DataValidationErrorStyleStop, column.Aliases[0], fmt.Sprintf("Must be %d to %d characters long", minimum, maximum))
+	}
+
+	return validation, nil
+}
+
+// describe lists the rules of field the way the instructions sheet shows them.
+func (schema ImportSchema) describe(field string) []string {
+	var rules []string
+	if schema.HasRule(field, "required") {
+		rules = append(rules, "Required")
+	} else {
+		rules = append(rules, "Optional")
+	}
+	if schema.HasRule(field, "unique") {
+		rules = append(rules, "Unique, in the file and among existing customers")
+	}
+	if schema.HasRule(field, "email") {
+		rules = append(rules, "A valid email address")
+	}
+	if values, ok := schema.Rule(field, "oneof"); ok {
+		rules = append(rules, "One of: "+strings.Join(strings.Fields(values), ", "))
+	}
+	if size, ok := schema.Rule(field, "len"); ok {
+		rules = append(rules, fmt.Sprintf("Exactly %s characters", size))
+	}
+	if size, ok := schema.Rule(field, "min"); ok {
+		rules = append(rules, fmt.Sprintf("At least %s characters", size))
+	}
+	if size, ok := schema.Rule(field, "max"); ok {
+		rules = append(rules, fmt.Sprintf("At most %s characters", size))
+	}
+	return rules
+}
+
+func (schema ImportSchema) instructions(file *excelize.File, sheet string, headerStyle int) error {
+	const instructions = "Instructions"
+	if _, err := file.NewSheet(instructions); err != nil {
+		return err
+	}
+
+	rows := [][]interface{}{
+		{fmt.Sprintf("Fill the %s sheet, one customer per row below the header. Columns may be in any order, this sheet is never read.", sheet)},
+		{},
+		{"Column", "Rules", "Also accepted as", "Description"},
+	}
+	header := len(rows)
+	for _, column := range schema.Columns {
+		rows = append(rows, []interface{}{
+			column.Aliases[0],
+			strings.Join(schema.describe(column.Field), "; "),
+			strings.Join(column.Aliases[1:], ", "),
+			column.Description,
+		})
+	}
+
+	if len(schema.Examples) > 0 {
+		rows = append(rows, []interface{}{}, []interface{}{"Example rows"})
+		titles := make([]interface{}, 0, len(schema.Columns))
+		for _, column := range schema.Columns {
+			titles = append(titles, column.Aliases[0])
+		}
+		rows = append(rows, titles)
+		for _, example := range schema.Examples {
+			row := make([]interface{}, 0, len(example))
+			for _, value := range example {
+				row = append(row, value)
+			}
+			rows = append(rows, row)
+		}
+	}
+
+	for i, row := range rows {
+		cell, err := excelize.CoordinatesToCellName(1, i+1)
+		if err != nil {
+			return err
+		}
+		if err := file.SetSheetRow(instructions, cell, &row); err != nil {
+			return err
+		}
+	}
+
+	if err := file.SetCellStyle(instructions, fmt.Sprintf("A%d", header), fmt.Sprintf("D%d", header), headerStyle); err != nil {
+		return err
+	}
+	if len(schema.Examples) > 0 {
+		last, err := excelize.ColumnNumberToName(len(schema.Columns))
+		if err != nil {
+			return err
+		}
+		examplesHeader := len(rows) - len(schema.Examples)
+		if err := file.SetCellStyle(instructions, fmt.Sprintf("A%d", examplesHeader), fmt.Sprintf("%s%d", last, examplesHeader), headerStyle); err != nil {
+			return err
+		}
+	}
+	return file.SetColWidth(instructions, "A", "D", 40)
+}
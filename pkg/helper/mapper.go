@@ -2,9 +2,27 @@ package helper
 
 import (
 	"encoding/json"
+	"reflect"
+	"strings"
 )
 
 func Automapper(objOrigin interface{}, objDestination interface{}) {
 	jsonOrigin := StructToJson(objOrigin)
 	json.Unmarshal([]byte(jsonOrigin), objDestination)
 }
+
+// JsonFieldNames maps the json names of a struct's fields to their Go names, which is what
+// validator.StructPartial expects.
+func JsonFieldNames(obj interface{}) map[string]string {
+	names := map[string]string{}
+	objType := reflect.Indirect(reflect.ValueOf(obj)).Type()
+	for i := 0; i < objType.NumField(); i++ {
+		field := objType.Field(i)
+		name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		names[name] = field.Name
+	}
+	return names
+}
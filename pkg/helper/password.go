@@ -0,0 +1,24 @@
+package helper
+
+import "golang.org/x/crypto/bcrypt"
+
+func HashPassword(password string) (string, error) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hashed), nil
+}
+
+func ComparePassword(hashed string, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hashed), []byte(password)) == nil
+}
+
+// dummyHash has the cost of the stored hashes, comparing against it takes as long as a real check.
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
+
+// ComparePasswordDummy spends the time of a ComparePassword without a user to check against, so
+// an unknown username answers as slowly as a wrong password.
+func ComparePasswordDummy(password string) {
+	_ = bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+}
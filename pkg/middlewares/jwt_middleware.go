@@ -0,0 +1,51 @@
+package middlewares
+
+import (
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"scylla/pkg/exception"
+	"scylla/pkg/token"
+	"strings"
+)
+
+const ClaimsContextKey = "claims"
+
+// publicRoutes holds "METHOD /path" pairs that skip the JWT check, filled by Public.
+var publicRoutes = map[string]bool{}
+
+// Public opts a route out of JwtMiddleware, e.g. middlewares.Public(router.GET("/template", handler)).
+func Public(route *echo.Route) *echo.Route {
+	publicRoutes[route.Method+" "+route.Path] = true
+	return route
+}
+
+func JwtMiddleware(manager *token.Manager) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if publicRoutes[c.Request().Method+" "+c.Path()] || c.Request().Method == http.MethodOptions {
+				return next(c)
+			}
+
+			header := c.Request().Header.Get(echo.HeaderAuthorization)
+			scheme, tokenString, found := strings.Cut(header, " ")
+			if !found || !strings.EqualFold(scheme, "Bearer") || tokenString == "" {
+				return exception.NewUnauthorizedHandler("missing or malformed bearer token")
+			}
+
+			claims, err := manager.Parse(tokenString)
+			if err != nil {
+				// the parser's reason stays in the server log, clients only learn the token was refused
+				c.Logger().Warn("JwtMiddleware : ", err.Error())
+				return exception.NewUnauthorizedHandler("invalid or expired token")
+			}
+
+			c.Set(ClaimsContextKey, claims)
+			return next(c)
+		}
+	}
+}
+
+func GetClaims(c echo.Context) *token.Claims {
+	claims, _ := c.Get(ClaimsContextKey).(*token.Claims)
+	return claims
+}
@@ -0,0 +1,135 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"scylla/pkg/config"
+	"scylla/pkg/exception"
+	"scylla/pkg/token"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+)
+
+const testSecret = "a-secret-long-enough-for-hs256"
+
+func testManager(t *testing.T) *token.Manager {
+	t.Helper()
+	manager, err := token.NewManager(&config.Config{
+		JwtAlgorithm:      "HS256",
+		JwtSecretKey:      testSecret,
+		JwtIssuer:         "scylla",
+		JwtAccessTokenTTL: time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	return manager
+}
+
+// jwtServer guards /secret with JwtMiddleware and leaves /public open through Public.
+func jwtServer(t *testing.T) *echo.Echo {
+	app := echo.New()
+	app.HTTPErrorHandler = exception.ExceptionHandlers
+
+	group := app.Group("/jwt-test", JwtMiddleware(testManager(t)))
+	group.GET("/secret", func(c echo.Context) error {
+		return c.String(http.StatusOK, GetClaims(c).Username)
+	})
+	Public(group.GET("/public", func(c echo.Context) error {
+		return c.String(http.StatusOK, "open")
+	}))
+	return app
+}
+
+func serve(app *echo.Echo, method string, path string, authorization string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, path, nil)
+	if authorization != "" {
+		request.Header.Set(echo.HeaderAuthorization, authorization)
+	}
+	recorder := httptest.NewRecorder()
+	app.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func signClaims(t *testing.T, method jwt.SigningMethod, key interface{}, claims jwt.Claims) string {
+	t.Helper()
+	signed, err := jwt.NewWithClaims(method, claims).SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestJwtMiddleware(t *testing.T) {
+	app := jwtServer(t)
+
+	valid, _, err := testManager(t).GenerateAccessToken(7, "john", "admin", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims := func(issuer string, expiresAt *jwt.NumericDate) *token.Claims {
+		return &token.Claims{Username: "john", RegisteredClaims: jwt.RegisteredClaims{Issuer: issuer, ExpiresAt: expiresAt}}
+	}
+	soon := jwt.NewNumericDate(time.Now().Add(time.Minute))
+	past := jwt.NewNumericDate(time.Now().Add(-time.Minute))
+
+	tests := []struct {
+		name          string
+		authorization string
+		status        int
+		body          string
+	}{
+		{"valid token", "Bearer " + valid, http.StatusOK, "john"},
+		{"lower case scheme", "bearer " + valid, http.StatusOK, "john"},
+		{"missing header", "", http.StatusUnauthorized, "missing or malformed bearer token"},
+		{"basic scheme", "Basic am9objpzZWNyZXQ=", http.StatusUnauthorized, "missing or malformed bearer token"},
+		{"bare token", valid, http.StatusUnauthorized, "missing or malformed bearer token"},
+		{"empty bearer", "Bearer ", http.StatusUnauthorized, "missing or malformed bearer token"},
+		{"wrong alg", "Bearer " + signClaims(t, jwt.SigningMethodHS512, []byte(testSecret), claims("scylla", soon)), http.StatusUnauthorized, "invalid or expired token"},
+		{"alg none", "Bearer " + signClaims(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, claims("scylla", soon)), http.StatusUnauthorized, "invalid or expired token"},
+		{"wrong issuer", "Bearer " + signClaims(t, jwt.SigningMethodHS256, []byte(testSecret), claims("evil", soon)), http.StatusUnauthorized, "invalid or expired token"},
+		{"missing exp", "Bearer " + signClaims(t, jwt.SigningMethodHS256, []byte(testSecret), claims("scylla", nil)), http.StatusUnauthorized, "invalid or expired token"},
+		{"expired", "Bearer " + signClaims(t, jwt.SigningMethodHS256, []byte(testSecret), claims("scylla", past)), http.StatusUnauthorized, "invalid or expired token"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorder := serve(app, http.MethodGet, "/jwt-test/secret", test.authorization)
+			if recorder.Code != test.status {
+				t.Fatalf("status = %d, want %d: %s", recorder.Code, test.status, recorder.Body.String())
+			}
+			if !strings.Contains(recorder.Body.String(), test.body) {
+				t.Errorf("body = %s, want it to contain %q", recorder.Body.String(), test.body)
+			}
+		})
+	}
+}
+
+// TestJwtMiddlewareHidesReason makes sure the parser's reason never reaches the client.
+func TestJwtMiddlewareHidesReason(t *testing.T) {
+	expired := signClaims(t, jwt.SigningMethodHS256, []byte(testSecret), &token.Claims{RegisteredClaims: jwt.RegisteredClaims{
+		Issuer:    "scylla",
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Hour)),
+	}})
+
+	body := serve(jwtServer(t), http.MethodGet, "/jwt-test/secret", "Bearer "+expired).Body.String()
+	if strings.Contains(body, "token is expired") || strings.Contains(body, "invalid claims") {
+		t.Errorf("body = %s leaks the parser error", body)
+	}
+}
+
+func TestJwtMiddlewarePublic(t *testing.T) {
+	app := jwtServer(t)
+
+	if recorder := serve(app, http.MethodGet, "/jwt-test/public", ""); recorder.Code != http.StatusOK {
+		t.Errorf("public route status = %d, want 200", recorder.Code)
+	}
+	// Public is per method and path, not per prefix
+	if recorder := serve(app, http.MethodGet, "/jwt-test/secret", ""); recorder.Code != http.StatusUnauthorized {
+		t.Errorf("guarded route status = %d, want 401", recorder.Code)
+	}
+}
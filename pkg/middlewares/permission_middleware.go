@@ -0,0 +1,35 @@
+package middlewares
+
+import (
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"scylla/pkg/exception"
+)
+
+// RequirePermission only lets the request through when the JWT claims grant every listed permission.
+// It must run after JwtMiddleware, so declare it on the route rather than the group.
+func RequirePermission(permissions ...string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			claims := GetClaims(c)
+			if claims == nil {
+				return exception.NewUnauthorizedHandler("missing or malformed bearer token")
+			}
+
+			for _, permission := range permissions {
+				if !claims.HasPermission(permission) {
+					return exception.NewForbiddenHandler(fmt.Sprintf("missing permission %s", permission))
+				}
+			}
+
+			return next(c)
+		}
+	}
+}
+
+// HasPermission is the in-handler variant of RequirePermission, for options like include_deleted
+// that only some callers of a route may use.
+func HasPermission(c echo.Context, permission string) bool {
+	claims := GetClaims(c)
+	return claims != nil && claims.HasPermission(permission)
+}
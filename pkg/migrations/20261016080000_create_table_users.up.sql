@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS users (
+    id SERIAL PRIMARY KEY,
+    username VARCHAR(125) NOT NULL,
+    email VARCHAR(125) NULL,
+    password VARCHAR(255) NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT (now()),
+    updated_at timestamptz NULL,
+    CONSTRAINT unique_username UNIQUE (username)
+);
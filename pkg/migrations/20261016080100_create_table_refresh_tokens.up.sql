@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    token_hash VARCHAR(64) NOT NULL,
+    family_id UUID NOT NULL,
+    expires_at timestamptz NOT NULL,
+    revoked_at timestamptz NULL,
+    replaced_by INT NULL,
+    created_at timestamptz NOT NULL DEFAULT (now()),
+    CONSTRAINT unique_token_hash UNIQUE (token_hash)
+);
+
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family_id ON refresh_tokens (family_id);
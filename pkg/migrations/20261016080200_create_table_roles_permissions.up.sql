@@ -0,0 +1,43 @@
+CREATE TABLE IF NOT EXISTS roles (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(125) NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT (now()),
+    updated_at timestamptz NULL,
+    CONSTRAINT unique_role_name UNIQUE (name)
+);
+
+CREATE TABLE IF NOT EXISTS permissions (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(125) NOT NULL,
+    description VARCHAR(255) NULL,
+    created_at timestamptz NOT NULL DEFAULT (now()),
+    updated_at timestamptz NULL,
+    CONSTRAINT unique_permission_name UNIQUE (name)
+);
+
+CREATE TABLE IF NOT EXISTS role_permissions (
+    role_id INT NOT NULL REFERENCES roles (id) ON DELETE CASCADE,
+    permission_id INT NOT NULL REFERENCES permissions (id) ON DELETE CASCADE,
+    PRIMARY KEY (role_id, permission_id)
+);
+
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS role_id INT NULL REFERENCES roles (id) ON DELETE SET NULL;
+
+INSERT INTO roles (name) VALUES ('admin'), ('ops')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO permissions (name, description) VALUES
+    ('customers:read', 'List, view and export customers'),
+    ('customers:write', 'Create and update customers'),
+    ('customers:delete', 'Delete customers'),
+    ('customers:import', 'Import customers from a file')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id
+FROM roles r
+CROSS JOIN permissions p
+WHERE r.name = 'admin'
+   OR (r.name = 'ops' AND p.name IN ('customers:read', 'customers:write'))
+ON CONFLICT DO NOTHING;
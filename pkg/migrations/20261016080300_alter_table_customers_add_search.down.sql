@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_customers_address_trgm;
+DROP INDEX IF EXISTS idx_customers_phone_trgm;
+DROP INDEX IF EXISTS idx_customers_email_trgm;
+DROP INDEX IF EXISTS idx_customers_username_trgm;
+DROP INDEX IF EXISTS idx_customers_search_vector;
+ALTER TABLE customers
+DROP COLUMN IF EXISTS search_vector;
\ No newline at end of file
@@ -0,0 +1,12 @@
+DELETE FROM permissions WHERE name = 'customers:purge';
+
+-- before soft delete a deleted customer was gone; the rows still held here would come back to
+-- life once deleted_at is dropped, and their emails may be taken again, which UNIQUE (email) refuses
+DELETE FROM customers WHERE deleted_at IS NOT NULL;
+
+DROP INDEX IF EXISTS unique_email;
+ALTER TABLE customers
+    ADD CONSTRAINT unique_email UNIQUE (email);
+DROP INDEX IF EXISTS idx_customers_deleted_at;
+ALTER TABLE customers
+DROP COLUMN IF EXISTS deleted_at;
\ No newline at end of file
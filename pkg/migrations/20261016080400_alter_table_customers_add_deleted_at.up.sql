@@ -0,0 +1,20 @@
+ALTER TABLE customers
+    ADD COLUMN IF NOT EXISTS deleted_at timestamptz NULL;
+
+CREATE INDEX IF NOT EXISTS idx_customers_deleted_at ON customers (deleted_at);
+
+-- a soft deleted customer must not block its email from being reused
+ALTER TABLE customers
+DROP CONSTRAINT IF EXISTS unique_email;
+CREATE UNIQUE INDEX IF NOT EXISTS unique_email ON customers (email) WHERE deleted_at IS NULL;
+
+INSERT INTO permissions (name, description) VALUES
+    ('customers:purge', 'Permanently remove soft deleted customers')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id
+FROM roles r
+CROSS JOIN permissions p
+WHERE r.name = 'admin' AND p.name = 'customers:purge'
+ON CONFLICT DO NOTHING;
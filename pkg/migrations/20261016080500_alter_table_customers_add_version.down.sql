@@ -0,0 +1,2 @@
+ALTER TABLE customers
+DROP COLUMN IF EXISTS version;
\ No newline at end of file
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS export_jobs (
+    id UUID PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    state VARCHAR(16) NOT NULL DEFAULT 'queued',
+    filter JSONB NOT NULL DEFAULT '{}',
+    total_rows INT NOT NULL DEFAULT 0,
+    rows_written INT NOT NULL DEFAULT 0,
+    file_path VARCHAR(255) NULL,
+    error TEXT NULL,
+    expires_at timestamptz NULL,
+    finished_at timestamptz NULL,
+    created_at timestamptz NOT NULL DEFAULT (now()),
+    updated_at timestamptz NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_export_jobs_state ON export_jobs (state);
+CREATE INDEX IF NOT EXISTS idx_export_jobs_expires_at ON export_jobs (expires_at);
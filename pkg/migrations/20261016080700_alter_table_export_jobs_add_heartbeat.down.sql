@@ -0,0 +1,2 @@
+ALTER TABLE export_jobs
+DROP COLUMN IF EXISTS heartbeat_at;
\ No newline at end of file
@@ -0,0 +1,2 @@
+ALTER TABLE export_jobs
+    ADD COLUMN IF NOT EXISTS heartbeat_at timestamptz NULL;
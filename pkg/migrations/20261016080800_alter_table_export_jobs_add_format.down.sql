@@ -0,0 +1,4 @@
+ALTER TABLE export_jobs
+DROP COLUMN IF EXISTS format,
+DROP COLUMN IF EXISTS delimiter,
+DROP COLUMN IF EXISTS encoding;
\ No newline at end of file
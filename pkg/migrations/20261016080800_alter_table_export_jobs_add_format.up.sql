@@ -0,0 +1,4 @@
+ALTER TABLE export_jobs
+    ADD COLUMN IF NOT EXISTS format VARCHAR(8) NOT NULL DEFAULT 'xlsx',
+    ADD COLUMN IF NOT EXISTS delimiter VARCHAR(4) NULL,
+    ADD COLUMN IF NOT EXISTS encoding VARCHAR(16) NULL;
\ No newline at end of file
@@ -0,0 +1,2 @@
+ALTER TABLE export_jobs
+DROP COLUMN IF EXISTS instance;
\ No newline at end of file
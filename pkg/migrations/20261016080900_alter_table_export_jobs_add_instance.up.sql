@@ -0,0 +1,2 @@
+ALTER TABLE export_jobs
+    ADD COLUMN IF NOT EXISTS instance VARCHAR(255) NULL;
\ No newline at end of file
@@ -0,0 +1,117 @@
+package querybuilder
+
+import (
+	"fmt"
+	"scylla/pkg/exception"
+	"strings"
+)
+
+// Columns is the per-entity whitelist, mapping the field name clients send to the SQL column.
+// Nothing outside this map ever reaches the generated SQL as an identifier.
+type Columns map[string]string
+
+func (columns Columns) Column(field string) (string, error) {
+	column, ok := columns[field]
+	if !ok {
+		return "", exception.NewBadRequestHandler(fmt.Sprintf("field '%s' is not allowed", field))
+	}
+	return column, nil
+}
+
+// operators maps the filter operator name to its SQL template and the number of values it takes,
+// -1 meaning one or more.
+var operators = map[string]struct {
+	sql    string
+	values int
+}{
+	"eq":       {"%s = ?", 1},
+	"neq":      {"%s <> ?", 1},
+	"gt":       {"%s > ?", 1},
+	"gte":      {"%s >= ?", 1},
+	"lt":       {"%s < ?", 1},
+	"lte":      {"%s <= ?", 1},
+	"like":     {"%s LIKE ?", 1},
+	"ilike":    {"%s ILIKE ?", 1},
+	"contains": {"%s ILIKE ?", 1},
+	"in":       {"%s IN ?", -1},
+	"between":  {"%s BETWEEN ? AND ?", 2},
+	"is_null":  {"", 1},
+}
+
+type Builder struct {
+	columns    Columns
+	conditions []string
+	args       []interface{}
+	rank       string
+	rankArgs   []interface{}
+}
+
+func New(columns Columns) *Builder {
+	return &Builder{columns: columns}
+}
+
+// Where adds "field operator values" after checking both the field and the operator.
+func (builder *Builder) Where(field string, operator string, values ...interface{}) error {
+	column, err := builder.columns.Column(field)
+	if err != nil {
+		return err
+	}
+
+	op, ok := operators[operator]
+	if !ok {
+		return exception.NewBadRequestHandler(fmt.Sprintf("operator '%s' is not allowed on '%s'", operator, field))
+	}
+
+	if (op.values == -1 && len(values) == 0) || (op.values > 0 && len(values) != op.values) {
+		return exception.NewBadRequestHandler(fmt.Sprintf("operator '%s' on '%s' got %d values", operator, field, len(values)))
+	}
+
+	switch operator {
+	case "in":
+		builder.Raw(fmt.Sprintf(op.sql, column), values)
+	case "contains":
+		// a plain substring, % and _ in the value match themselves
+		builder.Raw(fmt.Sprintf(op.sql, column), "%"+EscapeLike(fmt.Sprint(values[0]))+"%")
+	case "is_null":
+		if isTrue(values[0]) {
+			builder.Raw(column + " IS NULL")
+		} else {
+			builder.Raw(column + " IS NOT NULL")
+		}
+	default:
+		builder.Raw(fmt.Sprintf(op.sql, column), values...)
+	}
+
+	return nil
+}
+
+// Raw adds a trusted condition, only use it for SQL that is not built from user input.
+func (builder *Builder) Raw(condition string, args ...interface{}) {
+	builder.conditions = append(builder.conditions, condition)
+	builder.args = append(builder.args, args...)
+}
+
+// WhereClause returns " WHERE a AND b" (or "" without conditions) and its arguments.
+func (builder *Builder) WhereClause() (string, []interface{}) {
+	args := append([]interface{}(nil), builder.args...)
+	if len(builder.conditions) == 0 {
+		return "", args
+	}
+	return " WHERE " + strings.Join(builder.conditions, " AND "), args
+}
+
+// Rank returns the relevance expression set by a ranked search mode, "" when there is none.
+// Its arguments come after the WHERE arguments, so render it after the WHERE clause.
+func (builder *Builder) Rank() (string, []interface{}) {
+	return builder.rank, builder.rankArgs
+}
+
+func isTrue(value interface{}) bool {
+	switch v := value.(type) {
+	case bool:
+		return v
+	case string:
+		return v == "true" || v == "1"
+	}
+	return false
+}
@@ -0,0 +1,142 @@
+package querybuilder
+
+import (
+	"reflect"
+	"scylla/entity"
+	"testing"
+)
+
+var testColumns = Columns{
+	"id":         "id",
+	"username":   "username",
+	"created_at": "created_at",
+}
+
+func TestWhere(t *testing.T) {
+	tests := []struct {
+		name     string
+		field    string
+		operator string
+		values   []interface{}
+		where    string
+		args     []interface{}
+	}{
+		{"eq", "username", "eq", []interface{}{"john"}, " WHERE username = ?", []interface{}{"john"}},
+		{"neq", "username", "neq", []interface{}{"john"}, " WHERE username <> ?", []interface{}{"john"}},
+		{"gte", "id", "gte", []interface{}{"10"}, " WHERE id >= ?", []interface{}{"10"}},
+		{"ilike", "username", "ilike", []interface{}{"%jo%"}, " WHERE username ILIKE ?", []interface{}{"%jo%"}},
+		{"contains escapes wildcards", "username", "contains", []interface{}{`50%_off\`}, " WHERE username ILIKE ?", []interface{}{`%50\%\_off\\%`}},
+		{"in keeps the values as one list argument", "id", "in", []interface{}{"1", "2"}, " WHERE id IN ?", []interface{}{[]interface{}{"1", "2"}}},
+		{"between", "created_at", "between", []interface{}{"2024-01-01", "2024-12-31"}, " WHERE created_at BETWEEN ? AND ?", []interface{}{"2024-01-01", "2024-12-31"}},
+		{"is_null true", "username", "is_null", []interface{}{"true"}, " WHERE username IS NULL", nil},
+		{"is_null false", "username", "is_null", []interface{}{false}, " WHERE username IS NOT NULL", nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			builder := New(testColumns)
+			if err := builder.Where(test.field, test.operator, test.values...); err != nil {
+				t.Fatalf("Where() error = %v", err)
+			}
+
+			where, args := builder.WhereClause()
+			if where != test.where {
+				t.Errorf("where = %q, want %q", where, test.where)
+			}
+			if len(args) != 0 || len(test.args) != 0 {
+				if !reflect.DeepEqual(args, test.args) {
+					t.Errorf("args = %#v, want %#v", args, test.args)
+				}
+			}
+		})
+	}
+}
+
+func TestWhereRejects(t *testing.T) {
+	tests := []struct {
+		name     string
+		field    string
+		operator string
+		values   []interface{}
+	}{
+		{"unknown field", "password", "eq", []interface{}{"x"}},
+		{"unknown operator", "username", "regex", []interface{}{"x"}},
+		{"missing value", "username", "eq", nil},
+		{"too many values", "username", "eq", []interface{}{"a", "b"}},
+		{"between with one value", "created_at", "between", []interface{}{"2024-01-01"}},
+		{"in without values", "id", "in", nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			builder := New(testColumns)
+			if err := builder.Where(test.field, test.operator, test.values...); err == nil {
+				t.Fatal("Where() error = nil, want an error")
+			}
+
+			if where, _ := builder.WhereClause(); where != "" {
+				t.Errorf("where = %q, want nothing added", where)
+			}
+		})
+	}
+}
+
+func TestWhereClauseJoinsConditions(t *testing.T) {
+	builder := New(testColumns)
+	if where, args := builder.WhereClause(); where != "" || len(args) != 0 {
+		t.Fatalf("empty builder = %q %v, want no clause", where, args)
+	}
+
+	builder.Raw("deleted_at IS NULL")
+	if err := builder.Where("username", "eq", "john"); err != nil {
+		t.Fatal(err)
+	}
+	if err := builder.Where("id", "lt", 5); err != nil {
+		t.Fatal(err)
+	}
+
+	where, args := builder.WhereClause()
+	if want := " WHERE deleted_at IS NULL AND username = ? AND id < ?"; where != want {
+		t.Errorf("where = %q, want %q", where, want)
+	}
+	if want := []interface{}{"john", 5}; !reflect.DeepEqual(args, want) {
+		t.Errorf("args = %#v, want %#v", args, want)
+	}
+}
+
+// TestApplyFilterPatterns makes sure only like and ilike read % and _ as wildcards, so filtering
+// on john_doe@x.com cannot match johnXdoe@x.com.
+func TestApplyFilterPatterns(t *testing.T) {
+	tests := []struct {
+		name     string
+		operator string
+		value    string
+		where    string
+		arg      string
+	}{
+		{"eq is literal", "eq", "john_doe@x.com", " WHERE username = ?", "john_doe@x.com"},
+		{"contains escapes", "contains", "john_doe@x.com", " WHERE username ILIKE ?", `%john\_doe@x.com%`},
+		{"contains escapes percent and backslash", "contains", `100%\`, " WHERE username ILIKE ?", `%100\%\\%`},
+		{"like is a pattern as sent", "like", "john_doe%", " WHERE username LIKE ?", "john_doe%"},
+		{"like is not widened", "like", "john", " WHERE username LIKE ?", "john"},
+		{"ilike is a pattern as sent", "ilike", "%@x.com", " WHERE username ILIKE ?", "%@x.com"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			builder := New(testColumns)
+			filter := entity.GeneralQueryFilter{Filters: Filters{"username": {test.operator: {test.value}}}}
+			if err := builder.Apply(filter, Spec{}); err != nil {
+				t.Fatalf("Apply() error = %v", err)
+			}
+
+			where, args := builder.WhereClause()
+			if where != test.where {
+				t.Errorf("where = %q, want %q", where, test.where)
+			}
+			if want := []interface{}{test.arg}; !reflect.DeepEqual(args, want) {
+				t.Errorf("args = %#v, want %#v", args, want)
+			}
+		})
+	}
+}
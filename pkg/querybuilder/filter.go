@@ -0,0 +1,203 @@
+package querybuilder
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"scylla/entity"
+	"scylla/pkg/exception"
+	"sort"
+	"strings"
+)
+
+// Filters is filter[field][operator]=value grouped as field -> operator -> values.
+type Filters map[string]map[string][]string
+
+var filterParam = regexp.MustCompile(`^filter\[([^\]]+)\]\[([^\]]+)\]$`)
+
+// ParseFilterParams picks the filter[field][operator] keys out of a query string, the
+// field and operator are only checked once the filters reach a Builder.
+func ParseFilterParams(params url.Values) Filters {
+	filters := Filters{}
+	for key, values := range params {
+		match := filterParam.FindStringSubmatch(key)
+		if match == nil {
+			continue
+		}
+
+		if filters[match[1]] == nil {
+			filters[match[1]] = map[string][]string{}
+		}
+		filters[match[1]][match[2]] = append(filters[match[1]][match[2]], values...)
+	}
+	return filters
+}
+
+// Spec describes how the generic list parameters map onto one entity.
+type Spec struct {
+	// SearchFields are the fields q looks in.
+	SearchFields []string
+	// SearchVector is the tsvector column behind mode=fulltext, empty when the entity has none.
+	SearchVector string
+	// ActiveField is the field is_active filters on, empty when the entity has none.
+	ActiveField string
+}
+
+// Apply adds the list parameters every entity shares: filter[field][op], q/mode and is_active.
+func (builder *Builder) Apply(filter entity.GeneralQueryFilter, spec Spec) error {
+	// walk the map in a fixed order so the same request always renders the same SQL
+	fields := make([]string, 0, len(filter.Filters))
+	for field := range filter.Filters {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	for _, field := range fields {
+		operations := make([]string, 0, len(filter.Filters[field]))
+		for operator := range filter.Filters[field] {
+			operations = append(operations, operator)
+		}
+		sort.Strings(operations)
+
+		for _, operator := range operations {
+			values := filterValues(operator, filter.Filters[field][operator])
+			if err := builder.Where(field, operator, values...); err != nil {
+				return err
+			}
+		}
+	}
+
+	if filter.Query != "" {
+		if err := builder.Search(filter.Query, filter.Mode, spec); err != nil {
+			return err
+		}
+	}
+
+	if filter.IsActive != nil {
+		if spec.ActiveField == "" {
+			return exception.NewBadRequestHandler("is_active is not supported here")
+		}
+		if err := builder.Where(spec.ActiveField, "eq", *filter.IsActive == 1); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Search matches q against the spec's search fields. mode is one of
+//   - contains (default), prefix, exact: ILIKE/equality on any search field
+//   - fulltext: prefix-matching every word of q against the spec's tsvector, ranked by ts_rank
+//   - fuzzy: trigram word similarity on any search field, so typos still match, ranked by similarity
+//
+// The ranked modes set Rank, which callers put in front of ORDER BY.
+func (builder *Builder) Search(query string, mode string, spec Spec) error {
+	switch mode {
+	case "fulltext":
+		if spec.SearchVector == "" {
+			return exception.NewBadRequestHandler("mode 'fulltext' is not supported here")
+		}
+
+		terms := tsqueryTerms(query)
+		if terms == "" {
+			return nil
+		}
+		builder.Raw(spec.SearchVector+" @@ to_tsquery('simple', ?)", terms)
+		builder.rank = "ts_rank(" + spec.SearchVector + ", to_tsquery('simple', ?))"
+		builder.rankArgs = []interface{}{terms}
+		return nil
+	case "fuzzy":
+		columns, err := builder.searchColumns(spec)
+		if err != nil {
+			return err
+		}
+
+		var ors, similarities []string
+		var args []interface{}
+		for _, column := range columns {
+			ors = append(ors, "? <% "+column)
+			similarities = append(similarities, "word_similarity(?, "+column+")")
+			args = append(args, query)
+		}
+		builder.Raw("("+strings.Join(ors, " OR ")+")", args...)
+		builder.rank = "GREATEST(" + strings.Join(similarities, ", ") + ")"
+		builder.rankArgs = args
+		return nil
+	}
+
+	var operator, value string
+	switch mode {
+	case "", "contains":
+		operator, value = "ILIKE", "%"+EscapeLike(query)+"%"
+	case "prefix":
+		operator, value = "ILIKE", EscapeLike(query)+"%"
+	case "exact":
+		operator, value = "=", query
+	default:
+		return exception.NewBadRequestHandler(fmt.Sprintf("mode '%s' is not allowed", mode))
+	}
+
+	columns, err := builder.searchColumns(spec)
+	if err != nil {
+		return err
+	}
+
+	var ors []string
+	var args []interface{}
+	for _, column := range columns {
+		ors = append(ors, fmt.Sprintf("%s %s ?", column, operator))
+		args = append(args, value)
+	}
+
+	if len(ors) > 0 {
+		builder.Raw("("+strings.Join(ors, " OR ")+")", args...)
+	}
+	return nil
+}
+
+func (builder *Builder) searchColumns(spec Spec) ([]string, error) {
+	var columns []string
+	for _, field := range spec.SearchFields {
+		column, err := builder.columns.Column(field)
+		if err != nil {
+			return nil, err
+		}
+		columns = append(columns, column)
+	}
+	return columns, nil
+}
+
+var tsqueryWord = regexp.MustCompile(`[\p{L}\p{N}]+`)
+
+// tsqueryTerms turns free text into "word:* & word2:*", keeping only letters and digits so
+// user input can never break the tsquery syntax.
+func tsqueryTerms(query string) string {
+	var terms []string
+	for _, word := range tsqueryWord.FindAllString(query, -1) {
+		terms = append(terms, strings.ToLower(word)+":*")
+	}
+	return strings.Join(terms, " & ")
+}
+
+// filterValues turns the raw query values into operator arguments: in and between take a
+// comma separated list. Only like and ilike read their value as a pattern, as sent.
+func filterValues(operator string, raw []string) []interface{} {
+	var values []interface{}
+	for _, value := range raw {
+		switch operator {
+		case "in", "between":
+			for _, item := range strings.Split(value, ",") {
+				values = append(values, item)
+			}
+		default:
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// EscapeLike makes value match itself in a LIKE pattern, with backslash as the escape character
+// Postgres uses by default.
+func EscapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
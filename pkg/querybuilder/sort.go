@@ -0,0 +1,90 @@
+package querybuilder
+
+import (
+	"fmt"
+	"scylla/pkg/exception"
+	"strings"
+)
+
+type Sort struct {
+	Field  string
+	Column string
+	Desc   bool
+}
+
+// ParseSort reads "field:asc,field2:desc" against the whitelist and always ends with the
+// tiebreaker field so the order is total, which keyset pagination needs to never skip or
+// repeat a row. Without any sort the tiebreaker is used descending.
+func ParseSort(raw string, columns Columns, tiebreaker string) ([]Sort, error) {
+	var sorts []Sort
+	hasTiebreaker := false
+	for _, row := range strings.Split(raw, ",") {
+		if strings.TrimSpace(row) == "" {
+			continue
+		}
+
+		field, direction, _ := strings.Cut(strings.TrimSpace(row), ":")
+		column, err := columns.Column(field)
+		if err != nil {
+			return nil, err
+		}
+
+		switch strings.ToLower(direction) {
+		case "", "asc":
+			sorts = append(sorts, Sort{Field: field, Column: column})
+		case "desc":
+			sorts = append(sorts, Sort{Field: field, Column: column, Desc: true})
+		default:
+			return nil, exception.NewBadRequestHandler(fmt.Sprintf("sort direction '%s' is not allowed", direction))
+		}
+		hasTiebreaker = hasTiebreaker || field == tiebreaker
+	}
+
+	if !hasTiebreaker {
+		column, err := columns.Column(tiebreaker)
+		if err != nil {
+			return nil, err
+		}
+		sorts = append(sorts, Sort{Field: tiebreaker, Column: column, Desc: len(sorts) == 0})
+	}
+
+	return sorts, nil
+}
+
+// OrderBy renders the ORDER BY list, flipping every direction when reverse is set.
+func OrderBy(sorts []Sort, reverse bool) string {
+	var clauses []string
+	for _, sort := range sorts {
+		direction := "ASC"
+		if sort.Desc != reverse {
+			direction = "DESC"
+		}
+		clauses = append(clauses, sort.Column+" "+direction)
+	}
+	return strings.Join(clauses, ", ")
+}
+
+// Keyset builds "(a > ?) OR (a = ? AND b > ?) ..." matching the rows after values in sort
+// order, or before them when backward.
+func Keyset(sorts []Sort, values []interface{}, backward bool) (string, []interface{}) {
+	var ors []string
+	var args []interface{}
+	for i, sort := range sorts {
+		var ands []string
+		for j := 0; j < i; j++ {
+			ands = append(ands, sorts[j].Column+" = ?")
+			args = append(args, values[j])
+		}
+
+		operator := ">"
+		if sort.Desc != backward {
+			operator = "<"
+		}
+		ands = append(ands, fmt.Sprintf("%s %s ?", sort.Column, operator))
+		args = append(args, values[i])
+
+		ors = append(ors, "("+strings.Join(ands, " AND ")+")")
+	}
+
+	return "(" + strings.Join(ors, " OR ") + ")", args
+}
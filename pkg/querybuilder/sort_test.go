@@ -0,0 +1,120 @@
+package querybuilder
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseSort(t *testing.T) {
+	tests := []struct {
+		name  string
+		raw   string
+		sorts []Sort
+	}{
+		{"empty uses the tiebreaker descending", "", []Sort{{Field: "id", Column: "id", Desc: true}}},
+		{"tiebreaker appended ascending", "username", []Sort{{Field: "username", Column: "username"}, {Field: "id", Column: "id"}}},
+		{"directions", "username:desc, created_at:ASC", []Sort{
+			{Field: "username", Column: "username", Desc: true},
+			{Field: "created_at", Column: "created_at"},
+			{Field: "id", Column: "id"},
+		}},
+		{"tiebreaker already present", "id:asc", []Sort{{Field: "id", Column: "id"}}},
+		{"blank items skipped", ",username,", []Sort{{Field: "username", Column: "username"}, {Field: "id", Column: "id"}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sorts, err := ParseSort(test.raw, testColumns, "id")
+			if err != nil {
+				t.Fatalf("ParseSort() error = %v", err)
+			}
+			if !reflect.DeepEqual(sorts, test.sorts) {
+				t.Errorf("sorts = %+v, want %+v", sorts, test.sorts)
+			}
+		})
+	}
+}
+
+func TestParseSortRejects(t *testing.T) {
+	for _, raw := range []string{"password", "username:sideways"} {
+		if _, err := ParseSort(raw, testColumns, "id"); err == nil {
+			t.Errorf("ParseSort(%q) error = nil, want an error", raw)
+		}
+	}
+}
+
+func TestOrderBy(t *testing.T) {
+	sorts := []Sort{{Field: "username", Column: "username", Desc: true}, {Field: "id", Column: "id"}}
+
+	if got, want := OrderBy(sorts, false), "username DESC, id ASC"; got != want {
+		t.Errorf("OrderBy() = %q, want %q", got, want)
+	}
+	if got, want := OrderBy(sorts, true), "username ASC, id DESC"; got != want {
+		t.Errorf("OrderBy(reverse) = %q, want %q", got, want)
+	}
+}
+
+func TestKeyset(t *testing.T) {
+	single := []Sort{{Field: "id", Column: "id", Desc: true}}
+	several := []Sort{
+		{Field: "created_at", Column: "created_at"},
+		{Field: "username", Column: "username", Desc: true},
+		{Field: "id", Column: "id"},
+	}
+
+	tests := []struct {
+		name      string
+		sorts     []Sort
+		values    []interface{}
+		backward  bool
+		condition string
+		args      []interface{}
+	}{
+		{"one column forward", single, []interface{}{10}, false, "((id < ?))", []interface{}{10}},
+		{"one column backward", single, []interface{}{10}, true, "((id > ?))", []interface{}{10}},
+		{
+			"several columns forward", several, []interface{}{"2024-01-01", "john", 7}, false,
+			"((created_at > ?) OR (created_at = ? AND username < ?) OR (created_at = ? AND username = ? AND id > ?))",
+			[]interface{}{"2024-01-01", "2024-01-01", "john", "2024-01-01", "john", 7},
+		},
+		{
+			"several columns backward", several, []interface{}{"2024-01-01", "john", 7}, true,
+			"((created_at < ?) OR (created_at = ? AND username > ?) OR (created_at = ? AND username = ? AND id < ?))",
+			[]interface{}{"2024-01-01", "2024-01-01", "john", "2024-01-01", "john", 7},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			condition, args := Keyset(test.sorts, test.values, test.backward)
+			if condition != test.condition {
+				t.Errorf("condition = %q, want %q", condition, test.condition)
+			}
+			if !reflect.DeepEqual(args, test.args) {
+				t.Errorf("args = %#v, want %#v", args, test.args)
+			}
+			if placeholders := strings.Count(condition, "?"); placeholders != len(args) {
+				t.Errorf("%d placeholders for %d args", placeholders, len(args))
+			}
+		})
+	}
+}
+
+// TestKeysetThroughBuilder makes sure the keyset arguments reach the builder one by one, passing
+// the slice itself would bind it to the first placeholder.
+func TestKeysetThroughBuilder(t *testing.T) {
+	sorts := []Sort{{Field: "username", Column: "username"}, {Field: "id", Column: "id"}}
+	condition, keysetArgs := Keyset(sorts, []interface{}{"john", 7}, false)
+
+	builder := New(testColumns)
+	builder.Raw(condition, keysetArgs...)
+
+	where, args := builder.WhereClause()
+	if placeholders := strings.Count(where, "?"); placeholders != len(args) {
+		t.Errorf("%d placeholders for %d args in %q", placeholders, len(args), where)
+	}
+	if want := []interface{}{"john", "john", 7}; !reflect.DeepEqual(args, want) {
+		t.Errorf("args = %#v, want %#v", args, want)
+	}
+}
@@ -0,0 +1,110 @@
+package tabular
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/transform"
+	"io"
+)
+
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+type csvWriter struct {
+	buffer  *bufio.Writer
+	csv     *csv.Writer
+	encoder io.WriteCloser
+	record  []string
+}
+
+// newCsvWriter buffers its output, so nothing reaches w before the first few kilobytes of rows.
+func newCsvWriter(w io.Writer, columns []Column, options Options) (Writer, error) {
+	writer := &csvWriter{buffer: bufio.NewWriter(w)}
+	w = writer.buffer
+
+	switch options.Encoding {
+	case EncodingUTF8BOM:
+		if _, err := w.Write(utf8BOM); err != nil {
+			return nil, err
+		}
+	case EncodingLatin1:
+		// characters Latin-1 cannot hold become a replacement character instead of failing the export
+		writer.encoder = transform.NewWriter(w, encoding.ReplaceUnsupported(charmap.ISO8859_1.NewEncoder()))
+		w = writer.encoder
+	}
+
+	writer.csv = csv.NewWriter(w)
+	if options.Delimiter != 0 {
+		writer.csv.Comma = options.Delimiter
+	}
+
+	var headers []interface{}
+	for _, column := range columns {
+		headers = append(headers, column.Title)
+	}
+	if err := writer.Write(headers); err != nil {
+		return nil, err
+	}
+
+	return writer, nil
+}
+
+func (writer *csvWriter) Write(values []interface{}) error {
+	writer.record = writer.record[:0]
+	for _, value := range values {
+		writer.record = append(writer.record, stringValue(value))
+	}
+	return writer.csv.Write(writer.record)
+}
+
+func (writer *csvWriter) Close() error {
+	writer.csv.Flush()
+	if err := writer.csv.Error(); err != nil {
+		return err
+	}
+	if writer.encoder != nil {
+		if err := writer.encoder.Close(); err != nil {
+			return err
+		}
+	}
+	return writer.buffer.Flush()
+}
+
+// Abort drops the buffered rows, the ones already flushed to the underlying writer stay there.
+func (writer *csvWriter) Abort() {
+	writer.buffer.Reset(io.Discard)
+}
+
+type csvReader struct {
+	csv *csv.Reader
+}
+
+func newCsvReader(r io.Reader, options Options) (Reader, error) {
+	buffered := bufio.NewReader(r)
+	if head, _ := buffered.Peek(len(utf8BOM)); bytes.Equal(head, utf8BOM) {
+		buffered.Discard(len(utf8BOM))
+	}
+
+	r = buffered
+	if options.Encoding == EncodingLatin1 {
+		r = charmap.ISO8859_1.NewDecoder().Reader(r)
+	}
+
+	reader := csv.NewReader(r)
+	if options.Delimiter != 0 {
+		reader.Comma = options.Delimiter
+	}
+	reader.FieldsPerRecord = -1
+
+	return &csvReader{csv: reader}, nil
+}
+
+func (reader *csvReader) Read() ([]string, error) {
+	return reader.csv.Read()
+}
+
+func (reader *csvReader) Close() error {
+	return nil
+}
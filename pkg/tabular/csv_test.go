@@ -0,0 +1,87 @@
+package tabular
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCsvWriter(t *testing.T) {
+	tests := []struct {
+		name    string
+		options Options
+		output  string
+	}{
+		{"utf-8", Options{Encoding: EncodingUTF8}, "ID,Name\n1,José\n2,\"a,b\"\n"},
+		{"bom", Options{Encoding: EncodingUTF8BOM}, "\xef\xbb\xbfID,Name\n1,José\n2,\"a,b\"\n"},
+		{"latin1 with semicolons", Options{Delimiter: ';', Encoding: EncodingLatin1}, "ID;Name\n1;Jos\xe9\n2;a,b\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var out bytes.Buffer
+			writer, err := NewWriter(FormatCsv, &out, testColumns, test.options)
+			if err != nil {
+				t.Fatalf("NewWriter() error = %v", err)
+			}
+			for _, row := range [][]interface{}{{1, "José"}, {2, "a,b"}} {
+				if err := writer.Write(row); err != nil {
+					t.Fatalf("Write() error = %v", err)
+				}
+			}
+			if err := writer.Close(); err != nil {
+				t.Fatalf("Close() error = %v", err)
+			}
+
+			if out.String() != test.output {
+				t.Errorf("output = %q, want %q", out.String(), test.output)
+			}
+		})
+	}
+}
+
+func TestCsvWriterReplacesWhatLatin1CannotHold(t *testing.T) {
+	var out bytes.Buffer
+	writer, err := NewWriter(FormatCsv, &out, testColumns, Options{Encoding: EncodingLatin1})
+	if err != nil {
+		t.Fatalf("NewWriter() error = %v", err)
+	}
+	if err := writer.Write([]interface{}{1, "日本"}); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	if want := "ID,Name\n1,\x1a\x1a\n"; out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+}
+
+func TestCsvReader(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		options Options
+		records [][]string
+	}{
+		{"plain", "ID,Name\n1,José\n", Options{}, [][]string{{"ID", "Name"}, {"1", "José"}}},
+		{"bom dropped", "\xef\xbb\xbfID,Name\n1,José\n", Options{}, [][]string{{"ID", "Name"}, {"1", "José"}}},
+		{"latin1", "ID;Name\n1;Jos\xe9\n", Options{Delimiter: ';', Encoding: EncodingLatin1}, [][]string{{"ID", "Name"}, {"1", "José"}}},
+		{"tab", "ID\tName\n1\t\"a\tb\"\n", Options{Delimiter: '\t'}, [][]string{{"ID", "Name"}, {"1", "a\tb"}}},
+		{"short records", "ID,Name\n1\n", Options{}, [][]string{{"ID", "Name"}, {"1"}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			reader, err := NewReader(FormatCsv, strings.NewReader(test.content), test.options)
+			if err != nil {
+				t.Fatalf("NewReader() error = %v", err)
+			}
+			if records := readAll(t, reader); !reflect.DeepEqual(records, test.records) {
+				t.Errorf("records = %q, want %q", records, test.records)
+			}
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package tabular
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"scylla/pkg/exception"
+)
+
+type jsonlWriter struct {
+	out  *bufio.Writer
+	keys [][]byte
+	line bytes.Buffer
+}
+
+func newJsonlWriter(w io.Writer, columns []Column) (Writer, error) {
+	writer := &jsonlWriter{out: bufio.NewWriter(w)}
+	for _, column := range columns {
+		key, err := json.Marshal(column.Key)
+		if err != nil {
+			return nil, err
+		}
+		writer.keys = append(writer.keys, key)
+	}
+	return writer, nil
+}
+
+// Write builds the object by hand so the properties keep the column order.
+func (writer *jsonlWriter) Write(values []interface{}) error {
+	writer.line.Reset()
+	writer.line.WriteByte('{')
+	for i, value := range values {
+		if i >= len(writer.keys) {
+			break
+		}
+		if i > 0 {
+			writer.line.WriteByte(',')
+		}
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		writer.line.Write(writer.keys[i])
+		writer.line.WriteByte(':')
+		writer.line.Write(encoded)
+	}
+	writer.line.WriteString("}\n")
+
+	_, err := writer.out.Write(writer.line.Bytes())
+	return err
+}
+
+func (writer *jsonlWriter) Close() error {
+	return writer.out.Flush()
+}
+
+// Abort drops the buffered lines, the ones already flushed to the underlying writer stay there.
+func (writer *jsonlWriter) Abort() {
+	writer.out.Reset(io.Discard)
+}
+
+// jsonlReader takes its header from the property names of the first object, in document order;
+// properties that only show up in later lines are ignored.
+type jsonlReader struct {
+	scanner *bufio.Scanner
+	keys    []string
+	pending []string
+	line    int
+}
+
+func newJsonlReader(r io.Reader) (Reader, error) {
+	buffered := bufio.NewReader(r)
+	if head, _ := buffered.Peek(len(utf8BOM)); bytes.Equal(head, utf8BOM) {
+		buffered.Discard(len(utf8BOM))
+	}
+
+	scanner := bufio.NewScanner(buffered)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	return &jsonlReader{scanner: scanner}, nil
+}
+
+func (reader *jsonlReader) Read() ([]string, error) {
+	if reader.pending != nil {
+		record := reader.pending
+		reader.pending = nil
+		return record, nil
+	}
+
+	for reader.scanner.Scan() {
+		reader.line++
+		line := bytes.TrimSpace(reader.scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		if reader.keys == nil {
+			keys, err := objectKeys(line)
+			if err != nil {
+				return nil, reader.lineError(err)
+			}
+			reader.keys = keys
+			record, err := reader.values(line)
+			if err != nil {
+				return nil, err
+			}
+			reader.pending = record
+			return append([]string(nil), keys...), nil
+		}
+
+		return reader.values(line)
+	}
+
+	if err := reader.scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+func (reader *jsonlReader) Close() error {
+	return nil
+}
+
+func (reader *jsonlReader) values(line []byte) ([]string, error) {
+	decoder := json.NewDecoder(bytes.NewReader(line))
+	decoder.UseNumber()
+
+	var object map[string]interface{}
+	if err := decoder.Decode(&object); err != nil {
+		return nil, reader.lineError(err)
+	}
+
+	record := make([]string, len(reader.keys))
+	for i, key := range reader.keys {
+		switch value := object[key].(type) {
+		case nil:
+		case string:
+			record[i] = value
+		case json.Number, bool:
+			record[i] = fmt.Sprint(value)
+		default:
+			encoded, _ := json.Marshal(value)
+			record[i] = string(encoded)
+		}
+	}
+	return record, nil
+}
+
+func (reader *jsonlReader) lineError(err error) error {
+	return exception.NewBadRequestHandler(fmt.Sprintf("line %d is not a JSON object: %s", reader.line, err.Error()))
+}
+
+// objectKeys returns the top level property names of a JSON object in document order.
+func objectKeys(line []byte) ([]string, error) {
+	decoder := json.NewDecoder(bytes.NewReader(line))
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '{' {
+		return nil, fmt.Errorf("expected an object")
+	}
+
+	var keys []string
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, token.(string))
+
+		var skip json.RawMessage
+		if err := decoder.Decode(&skip); err != nil {
+			return nil, err
+		}
+	}
+	return keys, nil
+}
@@ -0,0 +1,103 @@
+package tabular
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestJsonlWriter(t *testing.T) {
+	var out bytes.Buffer
+	writer, err := NewWriter(FormatJsonl, &out, testColumns, Options{})
+	if err != nil {
+		t.Fatalf("NewWriter() error = %v", err)
+	}
+	for _, row := range [][]interface{}{{2, "zoë"}, {1, nil}} {
+		if err := writer.Write(row); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	// properties keep the column order rather than the alphabetical order of a map
+	if want := "{\"id\":2,\"name\":\"zoë\"}\n{\"id\":1,\"name\":null}\n"; out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+}
+
+func TestJsonlReader(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		records [][]string
+	}{
+		{
+			"header from the first object",
+			"{\"name\":\"john\",\"id\":1}\n{\"id\":2,\"name\":\"jane\"}\n",
+			[][]string{{"name", "id"}, {"john", "1"}, {"jane", "2"}},
+		},
+		{
+			"blank lines and bom",
+			"\xef\xbb\xbf{\"id\":1}\n\n  \n{\"id\":2}\n",
+			[][]string{{"id"}, {"1"}, {"2"}},
+		},
+		{
+			"value types",
+			`{"phone":"0812","big":12345678901234567890,"active":true,"missing":null,"tags":["a"]}`,
+			[][]string{{"phone", "big", "active", "missing", "tags"}, {"0812", "12345678901234567890", "true", "", `["a"]`}},
+		},
+		{
+			"later properties ignored",
+			"{\"id\":1}\n{\"id\":2,\"extra\":\"x\"}\n{}\n",
+			[][]string{{"id"}, {"1"}, {"2"}, {""}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			reader, err := NewReader(FormatJsonl, strings.NewReader(test.content), Options{})
+			if err != nil {
+				t.Fatalf("NewReader() error = %v", err)
+			}
+			if records := readAll(t, reader); !reflect.DeepEqual(records, test.records) {
+				t.Errorf("records = %q, want %q", records, test.records)
+			}
+		})
+	}
+}
+
+func TestJsonlReaderRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		message string
+	}{
+		{"array header", "[1,2]\n", "line 1 is not a JSON object"},
+		{"broken header", "{\"id\":\n", "line 1 is not a JSON object"},
+		{"broken row", "{\"id\":1}\n\n{\"id\":2\n", "line 3 is not a JSON object"},
+		{"scalar row", "{\"id\":1}\n42\n", "line 2 is not a JSON object"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			reader, err := NewReader(FormatJsonl, strings.NewReader(test.content), Options{})
+			if err != nil {
+				t.Fatalf("NewReader() error = %v", err)
+			}
+			defer reader.Close()
+
+			for {
+				_, err = reader.Read()
+				if err != nil {
+					break
+				}
+			}
+			if err == nil || !strings.Contains(err.Error(), test.message) {
+				t.Errorf("error = %v, want it to contain %q", err, test.message)
+			}
+		})
+	}
+}
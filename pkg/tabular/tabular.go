@@ -0,0 +1,176 @@
+package tabular
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/gabriel-vasile/mimetype"
+	"io"
+	"scylla/pkg/exception"
+)
+
+// Formats a table can be read from and written to.
+const (
+	FormatXlsx  = "xlsx"
+	FormatCsv   = "csv"
+	FormatJsonl = "jsonl"
+)
+
+// Encodings of the csv format, xlsx and jsonl are always UTF-8.
+const (
+	EncodingUTF8    = "utf-8"
+	EncodingUTF8BOM = "utf-8-bom"
+	EncodingLatin1  = "latin1"
+)
+
+// sniffLength is how much of a file Detect looks at, mimetype's default read limit.
+const sniffLength = 3072
+
+type Options struct {
+	// Delimiter separates csv fields, ',' when zero.
+	Delimiter rune
+	// Encoding is the csv character encoding, UTF-8 when empty. Reading always drops a UTF-8 BOM.
+	Encoding string
+	// Sheet is the xlsx worksheet, a reader falls back to the first sheet when it does not exist.
+	Sheet string
+}
+
+// Column is one column of a written table, xlsx and csv show Title in the header row, jsonl uses
+// Key as the property name.
+type Column struct {
+	Key   string
+	Title string
+}
+
+// Writer streams the rows of one table, the header is written when the writer is created.
+type Writer interface {
+	Write(values []interface{}) error
+	// Close finishes the table, it does not close the underlying io.Writer.
+	Close() error
+	// Abort frees the writer without finishing the table, whatever is still buffered is dropped.
+	Abort()
+}
+
+// Reader streams the records of one table, every value as a string.
+type Reader interface {
+	// Read returns the next record, the first one is the header, and io.EOF after the last.
+	// Records may be shorter than the header when trailing cells are empty.
+	Read() ([]string, error)
+	Close() error
+}
+
+func NewWriter(format string, w io.Writer, columns []Column, options Options) (Writer, error) {
+	switch format {
+	case FormatXlsx:
+		return newXlsxWriter(w, columns, options)
+	case FormatCsv:
+		return newCsvWriter(w, columns, options)
+	case FormatJsonl:
+		return newJsonlWriter(w, columns)
+	}
+	return nil, exception.NewBadRequestHandler(fmt.Sprintf("format '%s' is not supported", format))
+}
+
+func NewReader(format string, r io.Reader, options Options) (Reader, error) {
+	switch format {
+	case FormatXlsx:
+		return newXlsxReader(r, options)
+	case FormatCsv:
+		return newCsvReader(r, options)
+	case FormatJsonl:
+		return newJsonlReader(r)
+	}
+	return nil, exception.NewBadRequestHandler(fmt.Sprintf("format '%s' is not supported", format))
+}
+
+// Detect sniffs the format from the start of r. The returned reader yields the whole content
+// again, sniffed bytes included.
+func Detect(r io.Reader) (format string, content io.Reader, err error) {
+	head := make([]byte, sniffLength)
+	n, err := io.ReadFull(r, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", nil, err
+	}
+	head = head[:n]
+	content = io.MultiReader(bytes.NewReader(head), r)
+
+	detected := mimetype.Detect(head)
+	switch {
+	case detected.Is("application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"), detected.Is("application/zip"):
+		return FormatXlsx, content, nil
+	case detected.Is("application/x-ndjson"), detected.Is("application/json"):
+		return FormatJsonl, content, nil
+	}
+
+	// csv, tsv and anything else that is text; Latin-1 text may not be recognised as text at all
+	for mime := detected; mime != nil; mime = mime.Parent() {
+		if mime.Is("text/plain") {
+			return FormatCsv, content, nil
+		}
+	}
+	if detected.Is("application/octet-stream") && bytes.IndexByte(head, 0) == -1 {
+		return FormatCsv, content, nil
+	}
+
+	return "", nil, exception.NewBadRequestHandler(fmt.Sprintf("file type '%s' is not supported, use xlsx, csv or jsonl", detected.String()))
+}
+
+// ParseFormat checks a requested format, xlsx when empty.
+func ParseFormat(format string) (string, error) {
+	switch format {
+	case "":
+		return FormatXlsx, nil
+	case FormatXlsx, FormatCsv, FormatJsonl:
+		return format, nil
+	}
+	return "", exception.NewBadRequestHandler(fmt.Sprintf("format '%s' is not supported, use xlsx, csv or jsonl", format))
+}
+
+// ParseOptions reads the delimiter and encoding a client sends. delimiter is a single character
+// or "tab", encoding one of utf-8, utf-8-bom and latin1 (or iso-8859-1).
+func ParseOptions(delimiter string, encoding string) (Options, error) {
+	var options Options
+
+	switch {
+	case delimiter == "":
+	case delimiter == "tab" || delimiter == `\t`:
+		options.Delimiter = '\t'
+	case len([]rune(delimiter)) == 1 && delimiter != "\"" && delimiter != "\n" && delimiter != "\r":
+		options.Delimiter = []rune(delimiter)[0]
+	default:
+		return options, exception.NewBadRequestHandler(fmt.Sprintf("delimiter '%s' is not allowed", delimiter))
+	}
+
+	switch encoding {
+	case "", EncodingUTF8, "utf8":
+		options.Encoding = EncodingUTF8
+	case EncodingUTF8BOM:
+		options.Encoding = EncodingUTF8BOM
+	case EncodingLatin1, "iso-8859-1":
+		options.Encoding = EncodingLatin1
+	default:
+		return options, exception.NewBadRequestHandler(fmt.Sprintf("encoding '%s' is not supported", encoding))
+	}
+
+	return options, nil
+}
+
+// ContentType is the media type of a written table.
+func ContentType(format string, options Options) string {
+	switch format {
+	case FormatCsv:
+		if options.Encoding == EncodingLatin1 {
+			return "text/csv; charset=iso-8859-1"
+		}
+		return "text/csv; charset=utf-8"
+	case FormatJsonl:
+		return "application/x-ndjson"
+	}
+	return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+}
+
+func stringValue(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,180 @@
+package tabular
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// readAll drains reader, header included.
+func readAll(t *testing.T, reader Reader) [][]string {
+	t.Helper()
+	defer reader.Close()
+
+	var records [][]string
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return records
+		}
+		if err != nil {
+			t.Fatalf("Read() error = %v", err)
+		}
+		records = append(records, record)
+	}
+}
+
+var testColumns = []Column{{Key: "id", Title: "ID"}, {Key: "name", Title: "Name"}}
+
+func TestDetect(t *testing.T) {
+	var workbook bytes.Buffer
+	writer, err := NewWriter(FormatXlsx, &workbook, testColumns, Options{})
+	if err != nil {
+		t.Fatalf("NewWriter() error = %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		content []byte
+		format  string
+	}{
+		{"xlsx", workbook.Bytes(), FormatXlsx},
+		{"csv", []byte("ID,Name\n1,john\n"), FormatCsv},
+		{"tab separated", []byte("ID\tName\n1\tjohn\n"), FormatCsv},
+		{"latin1 csv", []byte("ID;Name\n1;Jos\xe9\n"), FormatCsv},
+		{"jsonl", []byte("{\"id\":1,\"name\":\"john\"}\n{\"id\":2,\"name\":\"jane\"}\n"), FormatJsonl},
+		{"single json object", []byte(`{"id":1,"name":"john"}`), FormatJsonl},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			format, content, err := Detect(bytes.NewReader(test.content))
+			if err != nil {
+				t.Fatalf("Detect() error = %v", err)
+			}
+			if format != test.format {
+				t.Errorf("format = %q, want %q", format, test.format)
+			}
+			// the sniffed bytes must not go missing from the content
+			read, _ := io.ReadAll(content)
+			if !bytes.Equal(read, test.content) {
+				t.Errorf("content lost bytes: read %d, want %d", len(read), len(test.content))
+			}
+		})
+	}
+}
+
+func TestDetectRejects(t *testing.T) {
+	binary := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	if _, _, err := Detect(bytes.NewReader(binary)); err == nil {
+		t.Error("Detect() error = nil for a png, want an error")
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	tests := []struct {
+		raw    string
+		format string
+	}{
+		{"", FormatXlsx},
+		{"xlsx", FormatXlsx},
+		{"csv", FormatCsv},
+		{"jsonl", FormatJsonl},
+	}
+
+	for _, test := range tests {
+		format, err := ParseFormat(test.raw)
+		if err != nil {
+			t.Errorf("ParseFormat(%q) error = %v", test.raw, err)
+		}
+		if format != test.format {
+			t.Errorf("ParseFormat(%q) = %q, want %q", test.raw, format, test.format)
+		}
+	}
+
+	for _, raw := range []string{"xls", "CSV", "json"} {
+		if _, err := ParseFormat(raw); err == nil {
+			t.Errorf("ParseFormat(%q) error = nil, want an error", raw)
+		}
+	}
+}
+
+func TestParseOptions(t *testing.T) {
+	tests := []struct {
+		name      string
+		delimiter string
+		encoding  string
+		options   Options
+	}{
+		{"defaults", "", "", Options{Encoding: EncodingUTF8}},
+		{"semicolon", ";", "", Options{Delimiter: ';', Encoding: EncodingUTF8}},
+		{"tab by name", "tab", "utf8", Options{Delimiter: '\t', Encoding: EncodingUTF8}},
+		{"escaped tab", `\t`, "utf-8-bom", Options{Delimiter: '\t', Encoding: EncodingUTF8BOM}},
+		{"multibyte delimiter", "§", "latin1", Options{Delimiter: '§', Encoding: EncodingLatin1}},
+		{"iso name", "|", "iso-8859-1", Options{Delimiter: '|', Encoding: EncodingLatin1}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			options, err := ParseOptions(test.delimiter, test.encoding)
+			if err != nil {
+				t.Fatalf("ParseOptions() error = %v", err)
+			}
+			if !reflect.DeepEqual(options, test.options) {
+				t.Errorf("options = %+v, want %+v", options, test.options)
+			}
+		})
+	}
+}
+
+func TestParseOptionsRejects(t *testing.T) {
+	tests := []struct {
+		delimiter string
+		encoding  string
+	}{
+		{";;", ""},
+		{`"`, ""},
+		{"\n", ""},
+		{"\r", ""},
+		{"", "utf-16"},
+	}
+
+	for _, test := range tests {
+		if _, err := ParseOptions(test.delimiter, test.encoding); err == nil {
+			t.Errorf("ParseOptions(%q, %q) error = nil, want an error", test.delimiter, test.encoding)
+		}
+	}
+}
+
+func TestContentType(t *testing.T) {
+	tests := []struct {
+		format      string
+		options     Options
+		contentType string
+	}{
+		{FormatXlsx, Options{}, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"},
+		{FormatCsv, Options{Encoding: EncodingUTF8BOM}, "text/csv; charset=utf-8"},
+		{FormatCsv, Options{Encoding: EncodingLatin1}, "text/csv; charset=iso-8859-1"},
+		{FormatJsonl, Options{}, "application/x-ndjson"},
+	}
+
+	for _, test := range tests {
+		if got := ContentType(test.format, test.options); got != test.contentType {
+			t.Errorf("ContentType(%q, %+v) = %q, want %q", test.format, test.options, got, test.contentType)
+		}
+	}
+}
+
+func TestUnsupportedFormat(t *testing.T) {
+	if _, err := NewWriter("xml", io.Discard, testColumns, Options{}); err == nil {
+		t.Error("NewWriter() error = nil, want an error")
+	}
+	if _, err := NewReader("xml", strings.NewReader(""), Options{}); err == nil {
+		t.Error("NewReader() error = nil, want an error")
+	}
+}
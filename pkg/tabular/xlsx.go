@@ -0,0 +1,136 @@
+package tabular
+
+import (
+	"github.com/xuri/excelize/v2"
+	"io"
+)
+
+type xlsxWriter struct {
+	out    io.Writer
+	excel  *excelize.File
+	stream *excelize.StreamWriter
+	row    int
+}
+
+// newXlsxWriter writes through excelize's StreamWriter, which keeps rows in memory up to a limit
+// and spills the rest to the OS temp dir until Close.
+func newXlsxWriter(w io.Writer, columns []Column, options Options) (Writer, error) {
+	sheet := options.Sheet
+	if sheet == "" {
+		sheet = "Sheet1"
+	}
+
+	excel := excelize.NewFile()
+	writer := &xlsxWriter{out: w, excel: excel, row: 1}
+	if sheet != "Sheet1" {
+		index, err := excel.NewSheet(sheet)
+		if err != nil {
+			excel.Close()
+			return nil, err
+		}
+		if err := excel.DeleteSheet("Sheet1"); err != nil {
+			excel.Close()
+			return nil, err
+		}
+		excel.SetActiveSheet(index)
+	}
+
+	stream, err := excel.NewStreamWriter(sheet)
+	if err != nil {
+		excel.Close()
+		return nil, err
+	}
+	writer.stream = stream
+
+	headerStyle, err := excel.NewStyle(&excelize.Style{
+		Font: &excelize.Font{Bold: true},
+		Fill: excelize.Fill{
+			Type:    "pattern",
+			Pattern: 1,
+			Color:   []string{"#FFFF00"},
+		},
+	})
+	if err != nil {
+		excel.Close()
+		return nil, err
+	}
+
+	var headers []interface{}
+	for _, column := range columns {
+		headers = append(headers, excelize.Cell{StyleID: headerStyle, Value: column.Title})
+	}
+	if err := writer.Write(headers); err != nil {
+		excel.Close()
+		return nil, err
+	}
+
+	return writer, nil
+}
+
+func (writer *xlsxWriter) Write(values []interface{}) error {
+	cell, err := excelize.CoordinatesToCellName(1, writer.row)
+	if err != nil {
+		return err
+	}
+	writer.row++
+	return writer.stream.SetRow(cell, values)
+}
+
+// Close writes the whole workbook, nothing reaches the underlying writer before.
+func (writer *xlsxWriter) Close() error {
+	defer writer.excel.Close()
+
+	if err := writer.stream.Flush(); err != nil {
+		return err
+	}
+	return writer.excel.Write(writer.out)
+}
+
+// Abort drops the workbook, the underlying writer never sees a byte of it.
+func (writer *xlsxWriter) Abort() {
+	writer.excel.Close()
+}
+
+type xlsxReader struct {
+	excel *excelize.File
+	rows  *excelize.Rows
+}
+
+// XlsxSheet is the sheet an xlsx Reader reads: sheet, or the first one when it is missing.
+func XlsxSheet(excel *excelize.File, sheet string) string {
+	if index, _ := excel.GetSheetIndex(sheet); sheet == "" || index == -1 {
+		return excel.GetSheetName(0)
+	}
+	return sheet
+}
+
+// newXlsxReader has to load the whole zip container, the rows are then read one by one.
+func newXlsxReader(r io.Reader, options Options) (Reader, error) {
+	excel, err := excelize.OpenReader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := excel.Rows(XlsxSheet(excel, options.Sheet))
+	if err != nil {
+		excel.Close()
+		return nil, err
+	}
+
+	return &xlsxReader{excel: excel, rows: rows}, nil
+}
+
+func (reader *xlsxReader) Read() ([]string, error) {
+	if !reader.rows.Next() {
+		if err := reader.rows.Error(); err != nil {
+			return nil, err
+		}
+		return nil, io.EOF
+	}
+	return reader.rows.Columns()
+}
+
+func (reader *xlsxReader) Close() error {
+	reader.rows.Close()
+	return reader.excel.Close()
+}
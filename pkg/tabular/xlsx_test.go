@@ -0,0 +1,76 @@
+package tabular
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+)
+
+func TestXlsxRoundTrip(t *testing.T) {
+	tests := []struct {
+		name      string
+		sheet     string
+		readSheet string
+	}{
+		{"default sheet", "", ""},
+		{"named sheet", "MST_CUSTOMER", "MST_CUSTOMER"},
+		{"missing sheet falls back to the first", "MST_CUSTOMER", "OTHER"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var workbook bytes.Buffer
+			writer, err := NewWriter(FormatXlsx, &workbook, testColumns, Options{Sheet: test.sheet})
+			if err != nil {
+				t.Fatalf("NewWriter() error = %v", err)
+			}
+			for _, row := range [][]interface{}{{1, "José"}, {2, nil}, {3, "0812"}} {
+				if err := writer.Write(row); err != nil {
+					t.Fatalf("Write() error = %v", err)
+				}
+			}
+			if workbook.Len() != 0 {
+				t.Error("the workbook reached the writer before Close")
+			}
+			if err := writer.Close(); err != nil {
+				t.Fatalf("Close() error = %v", err)
+			}
+
+			reader, err := NewReader(FormatXlsx, &workbook, Options{Sheet: test.readSheet})
+			if err != nil {
+				t.Fatalf("NewReader() error = %v", err)
+			}
+			want := [][]string{{"ID", "Name"}, {"1", "José"}, {"2"}, {"3", "0812"}}
+			if records := readAll(t, reader); !reflect.DeepEqual(records, want) {
+				t.Errorf("records = %q, want %q", records, want)
+			}
+		})
+	}
+}
+
+func TestXlsxSheet(t *testing.T) {
+	excel := excelize.NewFile()
+	defer excel.Close()
+	if _, err := excel.NewSheet("MST_CUSTOMER"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]string{
+		"":             "Sheet1",
+		"MST_CUSTOMER": "MST_CUSTOMER",
+		"missing":      "Sheet1",
+	}
+	for sheet, want := range tests {
+		if got := XlsxSheet(excel, sheet); got != want {
+			t.Errorf("XlsxSheet(%q) = %q, want %q", sheet, got, want)
+		}
+	}
+}
+
+func TestXlsxReaderRejects(t *testing.T) {
+	if _, err := NewReader(FormatXlsx, bytes.NewReader([]byte("ID,Name\n")), Options{}); err == nil {
+		t.Error("NewReader() error = nil for a csv, want an error")
+	}
+}
@@ -0,0 +1,151 @@
+package token
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"os"
+	"scylla/pkg/config"
+	"strconv"
+	"time"
+)
+
+type Claims struct {
+	UserID      int      `json:"user_id"`
+	Username    string   `json:"username"`
+	Role        string   `json:"role,omitempty"`
+	Permissions []string `json:"permissions,omitempty"`
+	jwt.RegisteredClaims
+}
+
+func (claims *Claims) HasPermission(permission string) bool {
+	for _, granted := range claims.Permissions {
+		if granted == permission {
+			return true
+		}
+	}
+	return false
+}
+
+type Manager struct {
+	method     jwt.SigningMethod
+	signKey    interface{}
+	verifyKey  interface{}
+	issuer     string
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+}
+
+func NewManager(config *config.Config) (*Manager, error) {
+	manager := &Manager{
+		issuer:     config.JwtIssuer,
+		accessTTL:  config.JwtAccessTokenTTL,
+		refreshTTL: config.JwtRefreshTokenTTL,
+	}
+
+	switch config.JwtAlgorithm {
+	case "", "HS256":
+		if config.JwtSecretKey == "" {
+			return nil, errors.New("JWT_SECRET_KEY is required for HS256")
+		}
+		manager.method = jwt.SigningMethodHS256
+		manager.signKey = []byte(config.JwtSecretKey)
+		manager.verifyKey = []byte(config.JwtSecretKey)
+	case "RS256":
+		publicPem, err := os.ReadFile(config.JwtPublicKeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("read JWT_PUBLIC_KEY_PATH: %w", err)
+		}
+		manager.method = jwt.SigningMethodRS256
+		manager.verifyKey, err = jwt.ParseRSAPublicKeyFromPEM(publicPem)
+		if err != nil {
+			return nil, err
+		}
+		// the private key is optional, a service that only verifies tokens does not need it
+		if config.JwtPrivateKeyPath != "" {
+			privatePem, err := os.ReadFile(config.JwtPrivateKeyPath)
+			if err != nil {
+				return nil, fmt.Errorf("read JWT_PRIVATE_KEY_PATH: %w", err)
+			}
+			manager.signKey, err = jwt.ParseRSAPrivateKeyFromPEM(privatePem)
+			if err != nil {
+				return nil, err
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unsupported JWT_ALGORITHM %q", config.JwtAlgorithm)
+	}
+
+	return manager, nil
+}
+
+func (manager *Manager) Parse(tokenString string) (*Claims, error) {
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{manager.method.Alg()}),
+		jwt.WithExpirationRequired(),
+	}
+	if manager.issuer != "" {
+		options = append(options, jwt.WithIssuer(manager.issuer))
+	}
+
+	claims := new(Claims)
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (interface{}, error) {
+		return manager.verifyKey, nil
+	}, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+func (manager *Manager) GenerateAccessToken(userID int, username string, role string, permissions []string) (string, time.Time, error) {
+	if manager.signKey == nil {
+		return "", time.Time{}, errors.New("JWT_PRIVATE_KEY_PATH is required to sign tokens")
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(manager.accessTTL)
+	claims := Claims{
+		UserID:      userID,
+		Username:    username,
+		Role:        role,
+		Permissions: permissions,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.New().String(),
+			Subject:   strconv.Itoa(userID),
+			Issuer:    manager.issuer,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+
+	signed, err := jwt.NewWithClaims(manager.method, claims).SignedString(manager.signKey)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return signed, expiresAt, nil
+}
+
+// GenerateRefreshToken returns an opaque random token, its hash for storage and its expiry.
+// Only the hash is persisted so a leaked table cannot be replayed.
+func (manager *Manager) GenerateRefreshToken() (raw string, hash string, expiresAt time.Time, err error) {
+	buf := make([]byte, 32)
+	if _, err = rand.Read(buf); err != nil {
+		return "", "", time.Time{}, err
+	}
+
+	raw = base64.RawURLEncoding.EncodeToString(buf)
+	return raw, HashRefreshToken(raw), time.Now().Add(manager.refreshTTL), nil
+}
+
+func HashRefreshToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,218 @@
+package token
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"scylla/pkg/config"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testSecret = "a-secret-long-enough-for-hs256"
+
+func hsManager(t *testing.T) *Manager {
+	t.Helper()
+	manager, err := NewManager(&config.Config{
+		JwtAlgorithm:       "HS256",
+		JwtSecretKey:       testSecret,
+		JwtIssuer:          "scylla",
+		JwtAccessTokenTTL:  time.Minute,
+		JwtRefreshTokenTTL: time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	return manager
+}
+
+// rsaKeys writes a fresh key pair as PEM files and returns their paths with the private key.
+func rsaKeys(t *testing.T) (privatePath string, publicPath string, key *rsa.PrivateKey) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicDer, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	privatePath = filepath.Join(dir, "private.pem")
+	publicPath = filepath.Join(dir, "public.pem")
+	privatePem := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	publicPem := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDer})
+	if err := os.WriteFile(privatePath, privatePem, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(publicPath, publicPem, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return privatePath, publicPath, key
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, key interface{}, claims jwt.Claims) string {
+	t.Helper()
+	signed, err := jwt.NewWithClaims(method, claims).SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestManagerRoundTrip(t *testing.T) {
+	privatePath, publicPath, _ := rsaKeys(t)
+	rs, err := NewManager(&config.Config{
+		JwtAlgorithm:      "RS256",
+		JwtPrivateKeyPath: privatePath,
+		JwtPublicKeyPath:  publicPath,
+		JwtIssuer:         "scylla",
+		JwtAccessTokenTTL: time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("NewManager(RS256) error = %v", err)
+	}
+
+	for name, manager := range map[string]*Manager{"HS256": hsManager(t), "RS256": rs} {
+		t.Run(name, func(t *testing.T) {
+			signed, expiresAt, err := manager.GenerateAccessToken(7, "john", "admin", []string{"customers:read"})
+			if err != nil {
+				t.Fatalf("GenerateAccessToken() error = %v", err)
+			}
+			if until := time.Until(expiresAt); until <= 0 || until > time.Minute {
+				t.Errorf("expires in %v, want within the access ttl", until)
+			}
+
+			claims, err := manager.Parse(signed)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if claims.UserID != 7 || claims.Username != "john" || claims.Role != "admin" || claims.Subject != "7" || claims.Issuer != "scylla" {
+				t.Errorf("claims = %+v", claims)
+			}
+			if !claims.HasPermission("customers:read") || claims.HasPermission("customers:write") {
+				t.Errorf("permissions = %v", claims.Permissions)
+			}
+		})
+	}
+}
+
+func TestManagerVerifyOnly(t *testing.T) {
+	_, publicPath, key := rsaKeys(t)
+	manager, err := NewManager(&config.Config{JwtAlgorithm: "RS256", JwtPublicKeyPath: publicPath})
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+
+	if _, _, err := manager.GenerateAccessToken(1, "john", "", nil); err == nil {
+		t.Error("GenerateAccessToken() error = nil without a private key")
+	}
+
+	signed := sign(t, jwt.SigningMethodRS256, key, jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute))})
+	if _, err := manager.Parse(signed); err != nil {
+		t.Errorf("Parse() error = %v for a token of the matching private key", err)
+	}
+}
+
+func TestManagerParseRejects(t *testing.T) {
+	manager := hsManager(t)
+	_, _, key := rsaKeys(t)
+
+	valid := func() jwt.RegisteredClaims {
+		return jwt.RegisteredClaims{Issuer: "scylla", ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute))}
+	}
+	wrongIssuer, expired, noExpiry := valid(), valid(), valid()
+	wrongIssuer.Issuer = "someone-else"
+	expired.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+	noExpiry.ExpiresAt = nil
+
+	signed := sign(t, jwt.SigningMethodHS256, []byte(testSecret), valid())
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"wrong secret", sign(t, jwt.SigningMethodHS256, []byte("another-secret"), valid())},
+		{"HS384", sign(t, jwt.SigningMethodHS384, []byte(testSecret), valid())},
+		{"RS256", sign(t, jwt.SigningMethodRS256, key, valid())},
+		{"alg none", sign(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, valid())},
+		{"wrong issuer", sign(t, jwt.SigningMethodHS256, []byte(testSecret), wrongIssuer)},
+		{"expired", sign(t, jwt.SigningMethodHS256, []byte(testSecret), expired)},
+		{"no expiry", sign(t, jwt.SigningMethodHS256, []byte(testSecret), noExpiry)},
+		{"tampered", signed[:len(signed)-2] + "xx"},
+		{"garbage", "not.a.token"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := manager.Parse(test.token); err == nil {
+				t.Error("Parse() error = nil, want the token refused")
+			}
+		})
+	}
+}
+
+func TestRS256RejectsHmacWithPublicKey(t *testing.T) {
+	_, publicPath, _ := rsaKeys(t)
+	manager, err := NewManager(&config.Config{JwtAlgorithm: "RS256", JwtPublicKeyPath: publicPath})
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	publicPem, err := os.ReadFile(publicPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the classic algorithm confusion: an HS256 token keyed with the published RSA key
+	forged := sign(t, jwt.SigningMethodHS256, publicPem, jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute))})
+	if _, err := manager.Parse(forged); err == nil {
+		t.Error("Parse() accepted an HS256 token on an RS256 manager")
+	}
+}
+
+func TestNewManagerRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  config.Config
+		message string
+	}{
+		{"hs256 without secret", config.Config{JwtAlgorithm: "HS256"}, "JWT_SECRET_KEY"},
+		{"rs256 without public key", config.Config{JwtAlgorithm: "RS256", JwtPublicKeyPath: filepath.Join(t.TempDir(), "missing.pem")}, "JWT_PUBLIC_KEY_PATH"},
+		{"unknown algorithm", config.Config{JwtAlgorithm: "ES256"}, "unsupported JWT_ALGORITHM"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewManager(&test.config)
+			if err == nil || !strings.Contains(err.Error(), test.message) {
+				t.Errorf("NewManager() error = %v, want it to mention %q", err, test.message)
+			}
+		})
+	}
+}
+
+func TestGenerateRefreshToken(t *testing.T) {
+	manager := hsManager(t)
+
+	raw, hash, expiresAt, err := manager.GenerateRefreshToken()
+	if err != nil {
+		t.Fatalf("GenerateRefreshToken() error = %v", err)
+	}
+	if hash != HashRefreshToken(raw) || hash == raw {
+		t.Errorf("hash = %q, want the sha256 of the raw token", hash)
+	}
+	if until := time.Until(expiresAt); until <= 59*time.Minute || until > time.Hour {
+		t.Errorf("expires in %v, want the refresh ttl", until)
+	}
+
+	other, _, _, _ := manager.GenerateRefreshToken()
+	if other == raw {
+		t.Error("two refresh tokens are equal")
+	}
+}
@@ -3,6 +3,8 @@ package utils
 import (
 	"github.com/labstack/echo/v4"
 	"scylla/entity"
+	"strconv"
+	"strings"
 )
 
 func ResponseInterceptor(ctx echo.Context, resp *entity.Response) {
@@ -14,3 +16,29 @@ func ErrorInterceptor(ctx echo.Context, resp *entity.Error) {
 	traceId := ctx.Response().Header().Get(echo.HeaderXRequestID)
 	resp.TraceID = traceId
 }
+
+// ETag renders a row version as a strong entity tag.
+func ETag(version int) string {
+	return strconv.Quote(strconv.Itoa(version))
+}
+
+// AnyETag is the version ParseETag returns for "If-Match: *", which matches whatever version the
+// customer currently has.
+const AnyETag = -1
+
+// ParseETag reads the version back out of an If-Match value. If-Match uses the strong comparison
+// (RFC 7232 section 3.1), so a weak W/ tag never matches and is refused like a malformed one.
+func ParseETag(header string) (version int, ok bool) {
+	header = strings.TrimSpace(header)
+	if header == "*" {
+		return AnyETag, true
+	}
+
+	value, err := strconv.Unquote(header)
+	if err != nil || !strings.HasPrefix(header, `"`) {
+		return 0, false
+	}
+
+	version, err = strconv.Atoi(value)
+	return version, err == nil && version >= 0
+}
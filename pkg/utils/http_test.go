@@ -0,0 +1,50 @@
+package utils
+
+import "testing"
+
+func TestETag(t *testing.T) {
+	tests := map[int]string{0: `"0"`, 1: `"1"`, 42: `"42"`}
+	for version, want := range tests {
+		if got := ETag(version); got != want {
+			t.Errorf("ETag(%d) = %s, want %s", version, got, want)
+		}
+	}
+}
+
+func TestParseETag(t *testing.T) {
+	tests := []struct {
+		header  string
+		version int
+		ok      bool
+	}{
+		{`"3"`, 3, true},
+		{` "3" `, 3, true},
+		{`"0"`, 0, true},
+		{"*", AnyETag, true},
+		{" * ", AnyETag, true},
+		{`W/"3"`, 0, false},
+		{`w/"3"`, 0, false},
+		{"3", 0, false},
+		{"`3`", 0, false},
+		{`"-1"`, 0, false},
+		{`"abc"`, 0, false},
+		{`"3", "4"`, 0, false},
+		{"", 0, false},
+	}
+
+	for _, test := range tests {
+		version, ok := ParseETag(test.header)
+		if ok != test.ok || (ok && version != test.version) {
+			t.Errorf("ParseETag(%q) = %d, %v, want %d, %v", test.header, version, ok, test.version, test.ok)
+		}
+	}
+}
+
+// TestETagRoundTrip checks that every ETag the API hands out is accepted back in If-Match.
+func TestETagRoundTrip(t *testing.T) {
+	for _, version := range []int{0, 1, 7, 123456} {
+		if got, ok := ParseETag(ETag(version)); !ok || got != version {
+			t.Errorf("ParseETag(ETag(%d)) = %d, %v", version, got, ok)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"context"
 	"github.com/go-playground/validator/v10"
 	"gorm.io/gorm"
 	"reflect"
@@ -12,15 +13,31 @@ var modelMap = map[string]reflect.Type{
 	"customers": reflect.TypeOf(model.Customer{}),
 }
 
+type uniqueCheckKey struct{}
+
+// WithoutUniqueCheck returns a context under which the unique validator always passes, for
+// callers that resolve duplicates in the database themselves, e.g. an upsert.
+func WithoutUniqueCheck(ctx context.Context) context.Context {
+	return context.WithValue(ctx, uniqueCheckKey{}, true)
+}
+
+func skipUniqueCheck(ctx context.Context) bool {
+	skip, _ := ctx.Value(uniqueCheckKey{}).(bool)
+	return skip
+}
+
 func ValidateUnique(db *gorm.DB, fl validator.FieldLevel) bool {
 	value := fl.Field().Interface()
 	tableName := getModelFromTag(fl)
 
-	exists := UniqueExistsInTable(db, value, tableName)
+	exists := UniqueExistsInTable(db, value, tableName, ownId(fl, tableName))
 	return !exists
 }
 
-func UniqueExistsInTable(db *gorm.DB, value interface{}, tableName string) bool {
+// UniqueExistsInTable tells whether value is already taken in "tableName;columnName". With a
+// third part, "tableName;columnName;columnID", the row whose columnID is exceptId is left out so
+// an update does not collide with the row it changes.
+func UniqueExistsInTable(db *gorm.DB, value interface{}, tableName string, exceptId interface{}) bool {
 	parts := strings.Split(tableName, ";")
 	modelName := parts[0]
 	columnName := parts[1]
@@ -33,10 +50,10 @@ func UniqueExistsInTable(db *gorm.DB, value interface{}, tableName string) bool
 	modelInstance := reflect.New(modelType).Interface()
 
 	var err error
-	if len(parts) > 2 {
-		err = db.Table(modelName).Where(columnName+" = ? AND "+parts[2], value).Debug().First(modelInstance).Error
+	if len(parts) > 2 && exceptId != nil {
+		err = db.Table(modelName).Where(columnName+" = ? AND "+parts[2]+" <> ?", value, exceptId).First(modelInstance).Error
 	} else {
-		err = db.Table(modelName).Where(columnName+" = ?", value).Debug().First(modelInstance).Error
+		err = db.Table(modelName).Where(columnName+" = ?", value).First(modelInstance).Error
 	}
 	if err != nil {
 		return false
@@ -44,6 +61,27 @@ func UniqueExistsInTable(db *gorm.DB, value interface{}, tableName string) bool
 	return true
 }
 
+// ownId returns the value of the field the third part of the tag names, matched on its json name,
+// or nil when the tag has none.
+func ownId(fl validator.FieldLevel, tableName string) interface{} {
+	parts := strings.Split(tableName, ";")
+	if len(parts) < 3 {
+		return nil
+	}
+
+	parent := reflect.Indirect(fl.Parent())
+	if parent.Kind() != reflect.Struct {
+		return nil
+	}
+	for i := 0; i < parent.NumField(); i++ {
+		name := strings.SplitN(parent.Type().Field(i).Tag.Get("json"), ",", 2)[0]
+		if name == parts[2] {
+			return parent.Field(i).Interface()
+		}
+	}
+	return nil
+}
+
 func getModelFromTag(fl validator.FieldLevel) string {
 	// Assuming 'validate' tag is in the format "unique=tableName;columnName;columnID" columnID is optional when update data
 	validateTag := fl.Param()
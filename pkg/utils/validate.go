@@ -1,13 +1,14 @@
 package utils
 
 import (
-	"fmt"
+	"context"
 	"github.com/go-playground/validator/v10"
 	"gorm.io/gorm"
 	"mime/multipart"
 	"path/filepath"
 	"reflect"
 	"regexp"
+	"strconv"
 	"strings"
 )
 
@@ -50,6 +51,15 @@ func InitializeValidator(db *gorm.DB) *validator.Validate {
 		return true
 	})
 
+	// maxBytes caps the length in bytes where max counts characters, e.g. for bcrypt's 72 byte limit
+	_ = validate.RegisterValidation("maxBytes", func(fl validator.FieldLevel) bool {
+		limit, err := strconv.Atoi(fl.Param())
+		if err != nil {
+			return false
+		}
+		return len(fl.Field().String()) <= limit
+	})
+
 	_ = validate.RegisterValidation("isString", func(fl validator.FieldLevel) bool {
 		_, ok := fl.Field().Interface().(string)
 		if !ok {
@@ -68,7 +78,10 @@ func InitializeValidator(db *gorm.DB) *validator.Validate {
 		return true
 	})
 
-	_ = validate.RegisterValidation("unique", func(fl validator.FieldLevel) bool {
+	_ = validate.RegisterValidationCtx("unique", func(ctx context.Context, fl validator.FieldLevel) bool {
+		if skipUniqueCheck(ctx) {
+			return true
+		}
 		return ValidateUnique(db, fl)
 	})
 
@@ -92,7 +105,6 @@ func InitializeValidator(db *gorm.DB) *validator.Validate {
 		}
 
 		fileExtension := getFileExtension(file.Filename)
-		fmt.Println("fileExtension", fileExtension)
 		return allowedExtensions[fileExtension]
 	})
 
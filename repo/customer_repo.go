@@ -2,24 +2,71 @@ package repo
 
 import (
 	"context"
+	"database/sql/driver"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/jackc/pgx/v5/pgconn"
 	"gorm.io/gorm"
 	"scylla/entity"
 	"scylla/model"
+	"scylla/pkg/exception"
 	"scylla/pkg/helper"
+	"scylla/pkg/querybuilder"
+	"sort"
 	"strings"
+	"time"
 )
 
 type CustomerRepo interface {
-	Insert(ctx context.Context, data model.Customer) error
-	InsertBatch(ctx context.Context, data []model.Customer, batchSize int) error
+	Insert(ctx context.Context, data model.Customer) (id int, err error)
+	InsertBatch(ctx context.Context, data []model.Customer, batchSize int, onConflict string) (result entity.BatchResult, err error)
 	Update(ctx context.Context, data model.Customer) error
+	UpdateBatch(ctx context.Context, data []model.Customer) error
+	UpdateByFilter(ctx context.Context, dataFilter entity.CustomerQueryFilter, set map[string]string, dryRun bool) (matched int, changed int, err error)
 	DeleteBatch(ctx context.Context, Id []int) error
+	RestoreBatch(ctx context.Context, Id []int) error
+	PurgeBatch(ctx context.Context, Id []int) error
 	FindById(ctx context.Context, Id int) (data model.Customer, err error)
+	FindByIds(ctx context.Context, Id []int) (data []model.Customer, err error)
 	FindAll(ctx context.Context, dataFilter entity.CustomerQueryFilter) (domain []entity.CustomerResponse, err error)
-	FindAllPaging(ctx context.Context, dataFilter entity.CustomerQueryFilter) (domain []entity.CustomerResponse)
-	CheckColumnExists(ctx context.Context, column string, value interface{}) bool
+	Count(ctx context.Context, dataFilter entity.CustomerQueryFilter) (total int, err error)
+	StreamAll(ctx context.Context, dataFilter entity.CustomerQueryFilter, fn func(customer entity.CustomerResponse) error) error
+	FindAllPaging(ctx context.Context, dataFilter entity.CustomerQueryFilter) (domain []entity.CustomerResponse, paging entity.Meta)
+	ExistingValues(ctx context.Context, column string, values []string) (existing map[string]bool, err error)
+}
+
+var (
+	ErrVersionConflict  = errors.New("customer was modified by someone else")
+	ErrEmailTaken       = errors.New("email has already been taken")
+	ErrCustomerNotFound = errors.New("record not found")
+)
+
+// customerColumns is the whitelist of fields clients may sort and filter customers on.
+var customerColumns = querybuilder.Columns{
+	"id":         "id",
+	"username":   "username",
+	"email":      "email",
+	"phone":      "phone",
+	"address":    "address",
+	"created_at": "created_at",
+}
+
+// customerSortColumns are the ORDER BY expressions behind the fields of customerColumns. The
+// nullable columns sort as their COALESCE with the empty string, so a keyset bound such as
+// "username > ?" neither skips nor repeats the NULL rows across pages.
+var customerSortColumns = querybuilder.Columns{
+	"id":         "id",
+	"username":   "COALESCE(username, '')",
+	"email":      "COALESCE(email, '')",
+	"phone":      "COALESCE(phone, '')",
+	"address":    "COALESCE(address, '')",
+	"created_at": "created_at",
+}
+
+var customerSpec = querybuilder.Spec{
+	SearchFields: []string{"username", "email", "phone", "address"},
+	SearchVector: "search_vector",
 }
 
 type CustomerRepoImpl struct {
@@ -30,23 +77,107 @@ func NewCustomerRepoImpl(db *gorm.DB) CustomerRepo {
 	return &CustomerRepoImpl{db: db}
 }
 
-func (repo *CustomerRepoImpl) Insert(ctx context.Context, data model.Customer) error {
+func (repo *CustomerRepoImpl) Insert(ctx context.Context, data model.Customer) (id int, err error) {
 	result := repo.db.WithContext(ctx).Create(&data)
+	if isUniqueViolation(result.Error) {
+		return 0, ErrEmailTaken
+	}
 	if result.Error != nil {
-		return result.Error
+		return 0, result.Error
 	}
-	return nil
+	return data.ID, nil
+}
+
+const maxInsertBatch = 1000
+
+// customerConflicts is the ON CONFLICT clause per on_conflict mode. The target repeats the
+// predicate of the partial unique_email index, Postgres cannot infer the index without it.
+var customerConflicts = map[string]string{
+	entity.OnConflictError: "",
+	entity.OnConflictSkip:  " ON CONFLICT (email) WHERE deleted_at IS NULL DO NOTHING",
+	entity.OnConflictUpdate: " ON CONFLICT (email) WHERE deleted_at IS NULL DO UPDATE SET " +
+		"username = EXCLUDED.username, phone = EXCLUDED.phone, address = EXCLUDED.address, " +
+		"updated_at = EXCLUDED.updated_at, version = customers.version + 1",
+}
+
+// InsertBatch inserts data in chunks of batchSize inside one transaction. onConflict decides what
+// happens to a row whose email is already taken: entity.OnConflictError fails the whole batch,
+// entity.OnConflictSkip keeps the existing customer and entity.OnConflictUpdate overwrites it.
+func (repo *CustomerRepoImpl) InsertBatch(ctx context.Context, data []model.Customer, batchSize int, onConflict string) (result entity.BatchResult, err error) {
+	conflict, ok := customerConflicts[onConflict]
+	if !ok {
+		return result, fmt.Errorf("unknown on_conflict mode '%s'", onConflict)
+	}
+
+	// every row takes six bind parameters and Postgres allows 65535 per statement
+	if batchSize <= 0 || batchSize > maxInsertBatch {
+		batchSize = maxInsertBatch
+	}
+
+	tx := repo.db.WithContext(ctx).Begin()
+	if tx.Error != nil {
+		return result, tx.Error
+	}
+
+	for start := 0; start < len(data); start += batchSize {
+		chunk := data[start:min(start+batchSize, len(data))]
+
+		var placeholders []string
+		var args []interface{}
+		now := time.Now()
+		for _, customer := range chunk {
+			placeholders = append(placeholders, "(?, ?, ?, ?, ?, ?)")
+			args = append(args, customer.Username, customer.Email, customer.Phone, customer.Address, now, now)
+		}
+
+		// xmax is only set on a row version created by an update, which tells the two apart
+		var inserted []bool
+		query := "INSERT INTO customers (username, email, phone, address, created_at, updated_at) VALUES " +
+			strings.Join(placeholders, ", ") + conflict + " RETURNING (xmax = 0)"
+		if err := tx.Raw(query, args...).Scan(&inserted).Error; err != nil {
+			tx.Rollback()
+			// only on_conflict=error can get here, unique_email is partial on deleted_at IS NULL so a
+			// soft deleted customer never holds an email
+			if isUniqueViolation(err) {
+				return entity.BatchResult{}, ErrEmailTaken
+			}
+			return entity.BatchResult{}, err
+		}
+
+		for _, isInsert := range inserted {
+			if isInsert {
+				result.Inserted++
+			} else {
+				result.Updated++
+			}
+		}
+		result.Skipped += len(chunk) - len(inserted)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return entity.BatchResult{}, err
+	}
+
+	return result, nil
+}
+
+// Update only applies while the row still carries data.Version, see updateCustomer.
+func (repo *CustomerRepoImpl) Update(ctx context.Context, data model.Customer) error {
+	return updateCustomer(repo.db.WithContext(ctx), data)
 }
 
-func (repo *CustomerRepoImpl) InsertBatch(ctx context.Context, data []model.Customer, batchSize int) error {
+// UpdateBatch runs Update for every customer in one transaction, a single conflict rolls back all.
+func (repo *CustomerRepoImpl) UpdateBatch(ctx context.Context, data []model.Customer) error {
 	tx := repo.db.WithContext(ctx).Begin()
 	if tx.Error != nil {
 		return tx.Error
 	}
 
-	if err := tx.CreateInBatches(&data, batchSize).Error; err != nil {
-		tx.Rollback()
-		return err
+	for _, customer := range data {
+		if err := updateCustomer(tx, customer); err != nil {
+			tx.Rollback()
+			return err
+		}
 	}
 
 	if err := tx.Commit().Error; err != nil {
@@ -56,37 +187,150 @@ func (repo *CustomerRepoImpl) InsertBatch(ctx context.Context, data []model.Cust
 	return nil
 }
 
-func (repo *CustomerRepoImpl) Update(ctx context.Context, data model.Customer) error {
-	result := repo.db.WithContext(ctx).Updates(&data)
-	if result.RowsAffected == 0 {
-		return errors.New("record not found")
+// UpdateByFilter assigns set to every customer matching dataFilter. Rows that already hold the
+// values are left alone so their version does not move; changed counts the others, and with
+// dryRun nothing is written.
+func (repo *CustomerRepoImpl) UpdateByFilter(ctx context.Context, dataFilter entity.CustomerQueryFilter, set map[string]string, dryRun bool) (matched int, changed int, err error) {
+	fields := make([]string, 0, len(set))
+	for field := range set {
+		fields = append(fields, field)
 	}
+	sort.Strings(fields)
 
+	var assignments, distinct []string
+	var setArgs, distinctArgs []interface{}
+	for _, field := range fields {
+		column, err := customerColumns.Column(field)
+		if err != nil {
+			return 0, 0, err
+		}
+		assignments = append(assignments, column+" = ?")
+		setArgs = append(setArgs, set[field])
+		distinct = append(distinct, column+" IS DISTINCT FROM ?")
+		distinctArgs = append(distinctArgs, set[field])
+	}
+
+	where, args := customerFilter(dataFilter).WhereClause()
+	changedWhere := where + " AND (" + strings.Join(distinct, " OR ") + ")"
+	changedArgs := append(args, distinctArgs...)
+
+	tx := repo.db.WithContext(ctx).Begin()
+	if tx.Error != nil {
+		return 0, 0, tx.Error
+	}
+
+	if err := tx.Raw("SELECT COUNT(*) FROM customers"+where, args...).Scan(&matched).Error; err != nil {
+		tx.Rollback()
+		return 0, 0, err
+	}
+
+	if dryRun {
+		err = tx.Raw("SELECT COUNT(*) FROM customers"+changedWhere, changedArgs...).Scan(&changed).Error
+		tx.Rollback()
+		return matched, changed, err
+	}
+
+	query := "UPDATE customers SET " + strings.Join(assignments, ", ") + ", version = version + 1, updated_at = ?" + changedWhere
+	result := tx.Exec(query, append(append(setArgs, time.Now()), changedArgs...)...)
+	if result.Error != nil {
+		tx.Rollback()
+		if isUniqueViolation(result.Error) {
+			return 0, 0, ErrEmailTaken
+		}
+		return 0, 0, result.Error
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return 0, 0, err
+	}
+
+	return matched, int(result.RowsAffected), nil
+}
+
+// updateCustomer only applies while the row still carries data.Version and bumps it by one, so a
+// concurrent edit in between makes it fail with ErrVersionConflict instead of being overwritten.
+func updateCustomer(db *gorm.DB, data model.Customer) error {
+	result := db.
+		Model(&data).
+		Where("version = ?", data.Version).
+		Updates(map[string]interface{}{
+			"username": data.Username,
+			"email":    data.Email,
+			"phone":    data.Phone,
+			"address":  data.Address,
+			"version":  gorm.Expr("version + 1"),
+		})
+	if isUniqueViolation(result.Error) {
+		return ErrEmailTaken
+	}
 	if result.Error != nil {
 		return result.Error
 	}
 
+	if result.RowsAffected == 0 {
+		return ErrVersionConflict
+	}
+
 	return nil
 }
 
 func (repo *CustomerRepoImpl) DeleteBatch(ctx context.Context, Id []int) error {
 	var data model.Customer
 	result := repo.db.WithContext(ctx).Where("id IN (?)", Id).Delete(&data)
+	if result.Error != nil {
+		return result.Error
+	}
+
 	if result.RowsAffected == 0 {
-		return errors.New("record not found")
+		return ErrCustomerNotFound
 	}
 
+	return nil
+}
+
+func (repo *CustomerRepoImpl) RestoreBatch(ctx context.Context, Id []int) error {
+	result := repo.db.WithContext(ctx).
+		Unscoped().
+		Model(&model.Customer{}).
+		Where("id IN (?) AND deleted_at IS NOT NULL", Id).
+		Update("deleted_at", nil)
+	// the email may have been given to another customer while this one was deleted
+	if isUniqueViolation(result.Error) {
+		return ErrEmailTaken
+	}
 	if result.Error != nil {
 		return result.Error
 	}
 
+	if result.RowsAffected == 0 {
+		return ErrCustomerNotFound
+	}
+
+	return nil
+}
+
+// PurgeBatch permanently removes customers, only rows that were soft deleted first qualify.
+func (repo *CustomerRepoImpl) PurgeBatch(ctx context.Context, Id []int) error {
+	var data model.Customer
+	result := repo.db.WithContext(ctx).
+		Unscoped().
+		Where("id IN (?) AND deleted_at IS NOT NULL", Id).
+		Delete(&data)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrCustomerNotFound
+	}
+
 	return nil
 }
 
 func (repo *CustomerRepoImpl) FindById(ctx context.Context, Id int) (data model.Customer, err error) {
 	result := repo.db.WithContext(ctx).First(&data, Id)
 	if result.RowsAffected == 0 {
-		return data, errors.New("record not found")
+		return data, ErrCustomerNotFound
 	}
 
 	if result.Error != nil {
@@ -96,103 +340,299 @@ func (repo *CustomerRepoImpl) FindById(ctx context.Context, Id int) (data model.
 	return data, nil
 }
 
+func (repo *CustomerRepoImpl) FindByIds(ctx context.Context, Id []int) (data []model.Customer, err error) {
+	result := repo.db.WithContext(ctx).Where("id IN ?", Id).Find(&data)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return data, nil
+}
+
 func (repo *CustomerRepoImpl) FindAll(ctx context.Context, dataFilter entity.CustomerQueryFilter) (domain []entity.CustomerResponse, err error) {
-	query := "SELECT id, username, email, phone, address, created_at FROM customers"
-	args := []interface{}{}
+	err = repo.StreamAll(ctx, dataFilter, func(customer entity.CustomerResponse) error {
+		domain = append(domain, customer)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
 
-	if dataFilter.Username != "" {
-		query += " WHERE username = ?"
-		args = append(args, dataFilter.Username)
+	return domain, nil
+}
+
+func (repo *CustomerRepoImpl) Count(ctx context.Context, dataFilter entity.CustomerQueryFilter) (total int, err error) {
+	where, args := customerFilter(dataFilter).WhereClause()
+	result := repo.db.WithContext(ctx).Raw("SELECT COUNT(*) FROM customers"+where, args...).Scan(&total)
+	if result.Error != nil {
+		return 0, result.Error
 	}
 
-	if dataFilter.Email != "" {
-		query += " WHERE email = ?"
-		args = append(args, dataFilter.Email)
+	return total, nil
+}
+
+// StreamAll hands every customer FindAll would return to fn while reading them off the database
+// cursor, so callers never hold the whole result in memory. An error from fn stops the walk. A
+// bad filter or sort comes back as the *exception.BadRequestStruct answering it.
+func (repo *CustomerRepoImpl) StreamAll(ctx context.Context, dataFilter entity.CustomerQueryFilter, fn func(customer entity.CustomerResponse) error) error {
+	query := "SELECT id, username, email, phone, address, version, created_at, deleted_at FROM customers"
+
+	builder, err := buildCustomerFilter(dataFilter)
+	if err != nil {
+		return err
 	}
+	where, args := builder.WhereClause()
+	query += where
 
-	if dataFilter.StartDate != "" && dataFilter.EndDate != "" {
-		query += " WHERE created_at BETWEEN ? AND ?"
-		args = append(args, dataFilter.StartDate, dataFilter.EndDate)
+	sorts, err := querybuilder.ParseSort(dataFilter.Sort, customerSortColumns, "id")
+	if err != nil {
+		return err
 	}
 
+	orderBy := querybuilder.OrderBy(sorts, false)
+	if rank, rankArgs := builder.Rank(); rank != "" && dataFilter.Sort == "" {
+		orderBy = rank + " DESC, " + orderBy
+		args = append(args, rankArgs...)
+	}
+	query += " ORDER BY " + orderBy
+
 	rows, err := repo.db.WithContext(ctx).Raw(query, args...).Rows()
 	if err != nil {
-		return nil, rows.Err()
+		return err
 	}
 	defer rows.Close()
 
 	for rows.Next() {
+		// gorm reads a NULL column as "", like the Scan of FindAllPaging, where rows.Scan would fail
 		var customer entity.CustomerResponse
-		err := rows.Scan(&customer.ID, &customer.Username, &customer.Email, &customer.Phone, &customer.Address, &customer.CreatedAt)
-		if err != nil {
-			return nil, err
+		if err := repo.db.ScanRows(rows, &customer); err != nil {
+			return err
+		}
+		if err := fn(customer); err != nil {
+			return err
 		}
-		domain = append(domain, customer)
 	}
 
-	return domain, nil
+	return rows.Err()
 }
 
-func (repo *CustomerRepoImpl) FindAllPaging(ctx context.Context, dataFilter entity.CustomerQueryFilter) (domain []entity.CustomerResponse) {
+// FindAllPaging fills TotalData and the cursors of the returned Meta, the caller owns page/limit.
+// With dataFilter.Cursor set it switches from OFFSET to keyset pagination on the sort columns.
+func (repo *CustomerRepoImpl) FindAllPaging(ctx context.Context, dataFilter entity.CustomerQueryFilter) (domain []entity.CustomerResponse, paging entity.Meta) {
 	rawQuery := `
 		SELECT 
-			id, username, email, phone, address, created_at
+			id, username, email, phone, address, version, created_at, deleted_at
 		FROM 
 			customers
 	`
+	countQuery := "SELECT COUNT(*) FROM customers"
 
-	var filters []string
-	var args []interface{}
+	builder := customerFilter(dataFilter)
 
-	if dataFilter.Username != "" {
-		filters = append(filters, "username LIKE ?")
-		args = append(args, "%"+dataFilter.Username+"%")
+	// the total must come from the same WHERE clause but without LIMIT/OFFSET or the keyset bound
+	where, args := builder.WhereClause()
+	result := repo.db.WithContext(ctx).Raw(countQuery+where, args...).Scan(&paging.TotalData)
+	helper.ErrorPanic(result.Error)
+
+	sorts, err := querybuilder.ParseSort(dataFilter.Sort, customerSortColumns, "id")
+	helper.ErrorPanic(err)
+
+	var cursor helper.Cursor
+	if dataFilter.Cursor != "" {
+		cursor, err = helper.DecodeCursor(dataFilter.Cursor)
+		if err != nil || cursor.Sort != dataFilter.Sort || !customerCursorValues(cursor.Values, sorts) {
+			panic(exception.NewBadRequestHandler("invalid cursor"))
+		}
+
+		condition, keysetArgs := querybuilder.Keyset(sorts, cursor.Values, cursor.Backward)
+		builder.Raw(condition, keysetArgs...)
 	}
-	if dataFilter.Email != "" {
-		filters = append(filters, "email LIKE ?")
-		args = append(args, "%"+dataFilter.Email+"%")
+
+	// walking backwards reads the rows in reverse order, they are flipped back below
+	where, args = builder.WhereClause()
+	orderBy := querybuilder.OrderBy(sorts, cursor.Backward)
+
+	// a ranked search orders by relevance unless the client asked for a sort; relevance is not
+	// a stable key, so such pages carry no cursors
+	rank, rankArgs := builder.Rank()
+	ranked := rank != "" && dataFilter.Sort == "" && dataFilter.Cursor == ""
+	if ranked {
+		orderBy = rank + " DESC, " + orderBy
+		args = append(args, rankArgs...)
 	}
-	if dataFilter.StartDate != "" && dataFilter.EndDate != "" {
-		filters = append(filters, "created_at BETWEEN ? AND ?")
-		args = append(args, dataFilter.StartDate, dataFilter.EndDate)
+	rawQuery += where + " ORDER BY " + orderBy
+
+	if dataFilter.Cursor != "" {
+		// one extra row tells whether there is another page in the walking direction
+		rawQuery += " LIMIT ?"
+		args = append(args, dataFilter.Limit+1)
+	} else if dataFilter.Limit > 0 && dataFilter.Page > 0 {
+		rawQuery += " LIMIT ? OFFSET ?"
+		args = append(args, dataFilter.Limit, (dataFilter.Page-1)*dataFilter.Limit)
 	}
 
-	if len(filters) > 0 {
-		rawQuery += " WHERE " + strings.Join(filters, " AND ")
-	}
+	result = repo.db.WithContext(ctx).Raw(rawQuery, args...).Scan(&domain)
+	helper.ErrorPanic(result.Error)
+
+	hasPrev := dataFilter.Page > 1
+	hasNext := dataFilter.Page*dataFilter.Limit < paging.TotalData
+	if dataFilter.Cursor != "" {
+		hasMore := len(domain) > dataFilter.Limit
+		if hasMore {
+			domain = domain[:dataFilter.Limit]
+		}
 
-	sortBy := "id DESC"
-	if dataFilter.Sort != "" {
-		var sortClauses []string
-		for _, row := range strings.Split(dataFilter.Sort, ",") {
-			colSort := strings.Split(row, ":")
-			if len(colSort) > 1 {
-				sortClauses = append(sortClauses, fmt.Sprintf("%s %s", colSort[0], colSort[1]))
+		if cursor.Backward {
+			for i, j := 0, len(domain)-1; i < j; i, j = i+1, j-1 {
+				domain[i], domain[j] = domain[j], domain[i]
 			}
+			hasPrev, hasNext = hasMore, true
+		} else {
+			hasPrev, hasNext = true, hasMore
+		}
+	}
+
+	if len(domain) > 0 && !ranked {
+		if hasNext {
+			paging.NextCursor = helper.EncodeCursor(helper.Cursor{
+				Values: customerSortValues(domain[len(domain)-1], sorts),
+				Sort:   dataFilter.Sort,
+			})
 		}
-		if len(sortClauses) > 0 {
-			sortBy = strings.Join(sortClauses, ", ")
+		if hasPrev {
+			paging.PrevCursor = helper.EncodeCursor(helper.Cursor{
+				Values:   customerSortValues(domain[0], sorts),
+				Sort:     dataFilter.Sort,
+				Backward: true,
+			})
 		}
 	}
-	rawQuery += " ORDER BY " + sortBy
 
-	if dataFilter.Limit > 0 && dataFilter.Page > 0 {
-		offset := (dataFilter.Page - 1) * dataFilter.Limit
-		rawQuery += fmt.Sprintf(" LIMIT %d OFFSET %d", dataFilter.Limit, offset)
+	return domain, paging
+}
+
+// existingValuesChunk is how many values ExistingValues sends in one query.
+const existingValuesChunk = 5000
+
+// ExistingValues tells which of values are already held by a live customer in column, with one
+// query per existingValuesChunk values rather than one per value.
+func (repo *CustomerRepoImpl) ExistingValues(ctx context.Context, column string, values []string) (map[string]bool, error) {
+	column, err := customerColumns.Column(column)
+	if err != nil {
+		return nil, err
 	}
 
-	result := repo.db.Raw(rawQuery, args...).WithContext(ctx).Scan(&domain)
-	helper.ErrorPanic(result.Error)
+	existing := map[string]bool{}
+	query := fmt.Sprintf("SELECT DISTINCT %s FROM customers WHERE %s = ANY(?::text[]) AND deleted_at IS NULL", column, column)
+	for start := 0; start < len(values); start += existingValuesChunk {
+		end := min(start+existingValuesChunk, len(values))
 
-	return domain
+		var found []string
+		err := repo.db.WithContext(ctx).Raw(query, textArray(values[start:end])).Scan(&found).Error
+		if err != nil {
+			return nil, err
+		}
+		for _, value := range found {
+			existing[value] = true
+		}
+	}
+	return existing, nil
 }
 
-func (repo *CustomerRepoImpl) CheckColumnExists(ctx context.Context, column string, value interface{}) bool {
-	var exists bool
-	query := fmt.Sprintf("SELECT EXISTS(SELECT 1 FROM customers WHERE %s = ?)", column)
-	err := repo.db.WithContext(ctx).Raw(query, value).Scan(&exists).Error
-	if err != nil {
+// textArray is sent as a single Postgres text[] parameter, gorm would expand a plain []string
+// into a list of parameters.
+type textArray []string
+
+func (array textArray) Value() (driver.Value, error) {
+	escape := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+	items := make([]string, len(array))
+	for i, item := range array {
+		items[i] = `"` + escape.Replace(item) + `"`
+	}
+	return "{" + strings.Join(items, ",") + "}", nil
+}
+
+// CheckCustomerFilter returns the 400 the customer queries would answer dataFilter with, for
+// callers that run them later, such as export jobs.
+func CheckCustomerFilter(dataFilter entity.CustomerQueryFilter) error {
+	if _, err := buildCustomerFilter(dataFilter); err != nil {
+		return err
+	}
+	_, err := querybuilder.ParseSort(dataFilter.Sort, customerSortColumns, "id")
+	return err
+}
+
+// customerFilter is the single place the list filters turn into SQL, FindAll (export) and
+// FindAllPaging both go through it so an export holds exactly the rows of the list view.
+func customerFilter(dataFilter entity.CustomerQueryFilter) *querybuilder.Builder {
+	builder, err := buildCustomerFilter(dataFilter)
+	helper.ErrorPanic(err)
+	return builder
+}
+
+func buildCustomerFilter(dataFilter entity.CustomerQueryFilter) (*querybuilder.Builder, error) {
+	builder := querybuilder.New(customerColumns)
+	if !dataFilter.IncludeDeleted {
+		builder.Raw("deleted_at IS NULL")
+	}
+	if dataFilter.Username != "" {
+		if err := builder.Where("username", "like", "%"+querybuilder.EscapeLike(dataFilter.Username)+"%"); err != nil {
+			return nil, err
+		}
+	}
+	if dataFilter.Email != "" {
+		if err := builder.Where("email", "like", "%"+querybuilder.EscapeLike(dataFilter.Email)+"%"); err != nil {
+			return nil, err
+		}
+	}
+	if dataFilter.StartDate != "" && dataFilter.EndDate != "" {
+		if err := builder.Where("created_at", "between", dataFilter.StartDate, dataFilter.EndDate); err != nil {
+			return nil, err
+		}
+	}
+	if err := builder.Apply(dataFilter.GeneralQueryFilter, customerSpec); err != nil {
+		return nil, err
+	}
+
+	return builder, nil
+}
+
+// customerSortValues picks the cursor values of customer for sorts. A NULL column comes back as
+// "" in CustomerResponse, the same value its COALESCE sorts on.
+func customerSortValues(customer entity.CustomerResponse, sorts []querybuilder.Sort) []interface{} {
+	var fields map[string]interface{}
+	_ = json.Unmarshal([]byte(helper.StructToJson(customer)), &fields)
+
+	values := make([]interface{}, len(sorts))
+	for i, sort := range sorts {
+		values[i] = fields[sort.Field]
+	}
+	return values
+}
+
+// customerCursorValues tells whether values fit sorts, a number for id and a string for every other
+// column, the way customerSortValues wrote them. The cursor comes from the client, anything else
+// would reach the database as is.
+func customerCursorValues(values []interface{}, sorts []querybuilder.Sort) bool {
+	if len(values) != len(sorts) {
 		return false
 	}
-	return exists
+	for i, sort := range sorts {
+		var ok bool
+		if sort.Field == "id" {
+			_, ok = values[i].(float64)
+		} else {
+			_, ok = values[i].(string)
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// isUniqueViolation reports whether err is Postgres rejecting a duplicate key.
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
 }
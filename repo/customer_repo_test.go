@@ -0,0 +1,431 @@
+package repo
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5/pgconn"
+	"reflect"
+	"scylla/entity"
+	"scylla/model"
+	"scylla/pkg/exception"
+	"scylla/pkg/helper"
+	"scylla/repo/repotest"
+	"strings"
+	"testing"
+)
+
+// customerFilterCase is one combination of the customer list filters and the WHERE clause it
+// must render.
+type customerFilterCase struct {
+	name   string
+	filter entity.CustomerQueryFilter
+	where  string
+	args   []interface{}
+}
+
+// customerFilterCases builds every combination of username, email, date range, filter[...] and q.
+func customerFilterCases() []customerFilterCase {
+	parts := []struct {
+		name      string
+		apply     func(filter *entity.CustomerQueryFilter)
+		condition string
+		args      []interface{}
+	}{
+		{"username", func(filter *entity.CustomerQueryFilter) { filter.Username = "john_doe" },
+			"username LIKE ?", []interface{}{`%john\_doe%`}},
+		{"email", func(filter *entity.CustomerQueryFilter) { filter.Email = "100%@example.com" },
+			"email LIKE ?", []interface{}{`%100\%@example.com%`}},
+		{"date range", func(filter *entity.CustomerQueryFilter) {
+			filter.StartDate, filter.EndDate = "2024-01-01", "2024-12-31"
+		}, "created_at BETWEEN ? AND ?", []interface{}{"2024-01-01", "2024-12-31"}},
+		{"filter", func(filter *entity.CustomerQueryFilter) {
+			filter.Filters = map[string]map[string][]string{"phone": {"eq": {"0812"}}}
+		}, "phone = ?", []interface{}{"0812"}},
+		{"q", func(filter *entity.CustomerQueryFilter) { filter.Query = "jo" },
+			"(username ILIKE ? OR email ILIKE ? OR phone ILIKE ? OR address ILIKE ?)",
+			[]interface{}{"%jo%", "%jo%", "%jo%", "%jo%"}},
+	}
+
+	var cases []customerFilterCase
+	for mask := 0; mask < 1<<len(parts); mask++ {
+		test := customerFilterCase{name: "none"}
+		conditions := []string{"deleted_at IS NULL"}
+		var names []string
+		for i, part := range parts {
+			if mask&(1<<i) == 0 {
+				continue
+			}
+			part.apply(&test.filter)
+			conditions = append(conditions, part.condition)
+			test.args = append(test.args, part.args...)
+			names = append(names, part.name)
+		}
+		if len(names) > 0 {
+			test.name = strings.Join(names, "+")
+		}
+		test.where = " WHERE " + strings.Join(conditions, " AND ")
+		cases = append(cases, test)
+	}
+	return cases
+}
+
+func TestCustomerFilter(t *testing.T) {
+	for _, test := range customerFilterCases() {
+		t.Run(test.name, func(t *testing.T) {
+			where, args := customerFilter(test.filter).WhereClause()
+			if where != test.where {
+				t.Errorf("where = %q, want %q", where, test.where)
+			}
+			if len(args)+len(test.args) > 0 && !reflect.DeepEqual(args, test.args) {
+				t.Errorf("args = %#v, want %#v", args, test.args)
+			}
+		})
+	}
+}
+
+func TestCustomerFilterIncludeDeleted(t *testing.T) {
+	where, _ := customerFilter(entity.CustomerQueryFilter{IncludeDeleted: true}).WhereClause()
+	if where != "" {
+		t.Errorf("where = %q, want no clause", where)
+	}
+}
+
+// whereOf cuts the WHERE clause out of a statement, up to ORDER BY or LIMIT.
+func whereOf(sql string) string {
+	start := strings.Index(sql, " WHERE ")
+	if start == -1 {
+		return ""
+	}
+	where := sql[start:]
+	for _, end := range []string{" ORDER BY ", " LIMIT "} {
+		if i := strings.Index(where, end); i != -1 {
+			where = where[:i]
+		}
+	}
+	return where
+}
+
+// numbered turns the ? placeholders into the $n the postgres dialector renders.
+func numbered(where string) string {
+	var builder strings.Builder
+	n := 0
+	for _, char := range where {
+		if char == '?' {
+			n++
+			builder.WriteString(fmt.Sprintf("$%d", n))
+			continue
+		}
+		builder.WriteRune(char)
+	}
+	return builder.String()
+}
+
+// TestCustomerQueriesShareFilter checks the export (FindAll), Count and both queries of
+// FindAllPaging filter on exactly the same clause.
+func TestCustomerQueriesShareFilter(t *testing.T) {
+	for _, test := range customerFilterCases() {
+		t.Run(test.name, func(t *testing.T) {
+			db, recorder := repotest.Open(t)
+			customerRepo := NewCustomerRepoImpl(db)
+			ctx := context.Background()
+
+			if _, err := customerRepo.FindAll(ctx, test.filter); err != nil {
+				t.Fatalf("FindAll() error = %v", err)
+			}
+			if _, err := customerRepo.Count(ctx, test.filter); err != nil {
+				t.Fatalf("Count() error = %v", err)
+			}
+			paged := test.filter
+			paged.Page, paged.Limit = 1, 10
+			customerRepo.FindAllPaging(ctx, paged)
+
+			if len(recorder.Queries) != 4 {
+				t.Fatalf("ran %d statements, want FindAll, Count and the two of FindAllPaging", len(recorder.Queries))
+			}
+			want := numbered(test.where)
+			for i, query := range recorder.Queries {
+				if where := whereOf(query.SQL); where != want {
+					t.Errorf("statement %d where = %q, want %q", i, where, want)
+				}
+				if len(test.args) > 0 && (len(query.Vars) < len(test.args) || !reflect.DeepEqual(query.Vars[:len(test.args)], test.args)) {
+					t.Errorf("statement %d vars = %#v, want them to start with %#v", i, query.Vars, test.args)
+				}
+			}
+		})
+	}
+}
+
+func TestFindAllPagingKeyset(t *testing.T) {
+	const selectFrom = "SELECT id, username, email, phone, address, version, created_at, deleted_at FROM customers WHERE deleted_at IS NULL AND "
+
+	tests := []struct {
+		name   string
+		sort   string
+		cursor helper.Cursor
+		sql    string
+		vars   []interface{}
+	}{
+		{
+			name:   "not null column",
+			sort:   "created_at:desc",
+			cursor: helper.Cursor{Values: []interface{}{"2024-01-01T00:00:00Z", 7}},
+			sql:    "((created_at < $1) OR (created_at = $2 AND id > $3)) ORDER BY created_at DESC, id ASC LIMIT $4",
+			vars:   []interface{}{"2024-01-01T00:00:00Z", "2024-01-01T00:00:00Z", float64(7), int64(11)},
+		},
+		{
+			name:   "mixed directions over nullable columns",
+			sort:   "username:asc,email:desc",
+			cursor: helper.Cursor{Values: []interface{}{"john", "", 7}},
+			sql: "((COALESCE(username, '') > $1) OR (COALESCE(username, '') = $2 AND COALESCE(email, '') < $3) " +
+				"OR (COALESCE(username, '') = $4 AND COALESCE(email, '') = $5 AND id > $6)) " +
+				"ORDER BY COALESCE(username, '') ASC, COALESCE(email, '') DESC, id ASC LIMIT $7",
+			vars: []interface{}{"john", "john", "", "john", "", float64(7), int64(11)},
+		},
+		{
+			name:   "mixed directions backward",
+			sort:   "phone:desc,created_at:asc,address:desc",
+			cursor: helper.Cursor{Values: []interface{}{"0812", "2024-01-01T00:00:00Z", "", 7}, Backward: true},
+			sql: "((COALESCE(phone, '') > $1) OR (COALESCE(phone, '') = $2 AND created_at < $3) " +
+				"OR (COALESCE(phone, '') = $4 AND created_at = $5 AND COALESCE(address, '') > $6) " +
+				"OR (COALESCE(phone, '') = $7 AND created_at = $8 AND COALESCE(address, '') = $9 AND id < $10)) " +
+				"ORDER BY COALESCE(phone, '') ASC, created_at DESC, COALESCE(address, '') ASC, id DESC LIMIT $11",
+			vars: []interface{}{
+				"0812", "0812", "2024-01-01T00:00:00Z", "0812", "2024-01-01T00:00:00Z", "",
+				"0812", "2024-01-01T00:00:00Z", "", float64(7), int64(11),
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, recorder := repotest.Open(t)
+			customerRepo := NewCustomerRepoImpl(db)
+
+			filter := entity.CustomerQueryFilter{}
+			filter.Sort, filter.Limit = test.sort, 10
+			test.cursor.Sort = test.sort
+			filter.Cursor = helper.EncodeCursor(test.cursor)
+			customerRepo.FindAllPaging(context.Background(), filter)
+
+			page := recorder.Queries[len(recorder.Queries)-1]
+			if got, want := strings.Join(strings.Fields(page.SQL), " "), selectFrom+test.sql; got != want {
+				t.Errorf("sql = %q, want %q", got, want)
+			}
+			if !reflect.DeepEqual(page.Vars, test.vars) {
+				t.Errorf("vars = %#v, want %#v", page.Vars, test.vars)
+			}
+		})
+	}
+}
+
+func TestFindAllPagingRejectsForeignCursor(t *testing.T) {
+	tests := []struct {
+		name   string
+		cursor helper.Cursor
+	}{
+		{"other sort", helper.Cursor{Values: []interface{}{"john", 7}, Sort: "email"}},
+		{"too few values", helper.Cursor{Values: []interface{}{"john"}, Sort: "username"}},
+		{"array value", helper.Cursor{Values: []interface{}{[]interface{}{"john"}, 7}, Sort: "username"}},
+		{"object value", helper.Cursor{Values: []interface{}{"john", map[string]interface{}{"id": 7}}, Sort: "username"}},
+		{"null value", helper.Cursor{Values: []interface{}{nil, 7}, Sort: "username"}},
+		{"number for a text column", helper.Cursor{Values: []interface{}{7, 7}, Sort: "username"}},
+		{"text for the id", helper.Cursor{Values: []interface{}{"john", "7"}, Sort: "username"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, recorder := repotest.Open(t)
+			customerRepo := NewCustomerRepoImpl(db)
+
+			filter := entity.CustomerQueryFilter{}
+			filter.Sort, filter.Limit = "username", 10
+			filter.Cursor = helper.EncodeCursor(test.cursor)
+
+			defer func() {
+				if _, ok := recover().(*exception.BadRequestStruct); !ok {
+					t.Error("the cursor was not rejected")
+				}
+				for _, query := range recorder.Queries {
+					if strings.Contains(query.SQL, "ORDER BY") {
+						t.Errorf("page query %q was sent", query.SQL)
+					}
+				}
+			}()
+			customerRepo.FindAllPaging(context.Background(), filter)
+		})
+	}
+}
+
+func TestStreamAllNullColumns(t *testing.T) {
+	db, recorder := repotest.Open(t)
+	recorder.Rows = func(query string, vars []interface{}) ([]string, [][]driver.Value) {
+		return []string{"id", "username", "email", "phone", "address", "version", "created_at", "deleted_at"},
+			[][]driver.Value{{int64(7), nil, "john@example.com", nil, nil, int64(1), "2024-01-01T00:00:00Z", nil}}
+	}
+
+	var customers []entity.CustomerResponse
+	err := NewCustomerRepoImpl(db).StreamAll(context.Background(), entity.CustomerQueryFilter{}, func(customer entity.CustomerResponse) error {
+		customers = append(customers, customer)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("StreamAll() error = %v", err)
+	}
+	want := []entity.CustomerResponse{{ID: 7, Email: "john@example.com", Version: 1, CreatedAt: "2024-01-01T00:00:00Z"}}
+	if !reflect.DeepEqual(customers, want) {
+		t.Errorf("customers = %+v, want %+v", customers, want)
+	}
+}
+
+func TestStreamAllRejectsBadSortAndFilter(t *testing.T) {
+	sorted := entity.CustomerQueryFilter{}
+	sorted.Sort = "password"
+	filtered := entity.CustomerQueryFilter{}
+	filtered.Filters = map[string]map[string][]string{"password": {"eq": {"x"}}}
+
+	for _, filter := range []entity.CustomerQueryFilter{sorted, filtered} {
+		db, recorder := repotest.Open(t)
+		err := NewCustomerRepoImpl(db).StreamAll(context.Background(), filter, func(customer entity.CustomerResponse) error { return nil })
+		if _, ok := err.(*exception.BadRequestStruct); !ok {
+			t.Errorf("StreamAll() error = %v, want a bad request", err)
+		}
+		if len(recorder.Queries) != 0 {
+			t.Errorf("sent %d queries, want none", len(recorder.Queries))
+		}
+	}
+}
+
+func TestInsertBatchUniqueViolation(t *testing.T) {
+	tests := []struct {
+		name     string
+		queryErr error
+		want     error
+	}{
+		{"unique violation", &pgconn.PgError{Code: "23505", ConstraintName: "unique_email"}, ErrEmailTaken},
+		{"other error", &pgconn.PgError{Code: "23502"}, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, recorder := repotest.Open(t)
+			recorder.QueryErr = test.queryErr
+
+			_, err := NewCustomerRepoImpl(db).InsertBatch(context.Background(), []model.Customer{{Email: "john@example.com"}}, 10, entity.OnConflictError)
+			if test.want != nil && !errors.Is(err, test.want) {
+				t.Errorf("InsertBatch() error = %v, want %v", err, test.want)
+			}
+			if test.want == nil && (err == nil || errors.Is(err, ErrEmailTaken)) {
+				t.Errorf("InsertBatch() error = %v, want the driver error", err)
+			}
+			if last := recorder.Queries[len(recorder.Queries)-1]; last.SQL != "ROLLBACK" {
+				t.Errorf("last statement = %q, want the transaction rolled back", last.SQL)
+			}
+		})
+	}
+}
+
+func TestCheckCustomerFilter(t *testing.T) {
+	valid := entity.CustomerQueryFilter{}
+	valid.Sort = "username:desc,created_at"
+	valid.Filters = map[string]map[string][]string{"email": {"contains": {"gmail"}}}
+	if err := CheckCustomerFilter(valid); err != nil {
+		t.Errorf("CheckCustomerFilter() error = %v", err)
+	}
+
+	badSort := entity.CustomerQueryFilter{}
+	badSort.Sort = "password"
+	badFilter := entity.CustomerQueryFilter{}
+	badFilter.Filters = map[string]map[string][]string{"email": {"regex": {".*"}}}
+	badMode := entity.CustomerQueryFilter{}
+	badMode.Query, badMode.Mode = "john", "soundex"
+
+	for name, filter := range map[string]entity.CustomerQueryFilter{"sort": badSort, "filter": badFilter, "mode": badMode} {
+		if _, ok := CheckCustomerFilter(filter).(*exception.BadRequestStruct); !ok {
+			t.Errorf("a bad %s was not rejected with a bad request", name)
+		}
+	}
+}
+
+func TestTextArray(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []string
+		want   string
+	}{
+		{"none", nil, `{}`},
+		{"plain", []string{"john@example.com", "jane@example.com"}, `{"john@example.com","jane@example.com"}`},
+		{"double quote", []string{`jo"hn`}, `{"jo\"hn"}`},
+		{"backslash", []string{`jo\hn`, `\"`}, `{"jo\\hn","\\\""}`},
+		{"comma", []string{"john,jane"}, `{"john,jane"}`},
+		{"braces", []string{"{john}", "}{"}, `{"{john}","}{"}`},
+		{"empty string", []string{"", "john"}, `{"","john"}`},
+		{"null", []string{"NULL"}, `{"NULL"}`},
+		{"spaces", []string{" john "}, `{" john "}`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			value, err := textArray(test.values).Value()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if value != test.want {
+				t.Errorf("Value() = %s, want %s", value, test.want)
+			}
+		})
+	}
+}
+
+func TestExistingValuesChunks(t *testing.T) {
+	tests := []struct {
+		name    string
+		values  int
+		queries int
+	}{
+		{"no values", 0, 0},
+		{"one value", 1, 1},
+		{"exactly one chunk", existingValuesChunk, 1},
+		{"one past a chunk", existingValuesChunk + 1, 2},
+		{"exactly two chunks", 2 * existingValuesChunk, 2},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			values := make([]string, test.values)
+			for i := range values {
+				values[i] = fmt.Sprintf("customer%d@example.com", i)
+			}
+
+			db, recorder := repotest.Open(t)
+			// the last value of every chunk is taken
+			recorder.Rows = func(query string, vars []interface{}) ([]string, [][]driver.Value) {
+				literal := strings.TrimSuffix(vars[0].(string), `"}`)
+				last := literal[strings.LastIndex(literal, `"`)+1:]
+				return []string{"email"}, [][]driver.Value{{last}}
+			}
+
+			existing, err := NewCustomerRepoImpl(db).ExistingValues(context.Background(), "email", values)
+			if err != nil {
+				t.Fatalf("ExistingValues() error = %v", err)
+			}
+			if len(recorder.Queries) != test.queries {
+				t.Fatalf("ran %d queries, want %d", len(recorder.Queries), test.queries)
+			}
+
+			want := map[string]bool{}
+			for i, query := range recorder.Queries {
+				end := min((i+1)*existingValuesChunk, len(values))
+				chunk, _ := textArray(values[i*existingValuesChunk : end]).Value()
+				if !strings.Contains(query.SQL, "email = ANY($1::text[])") || len(query.Vars) != 1 || query.Vars[0] != chunk {
+					t.Errorf("query %d = %q with %d values, want values %d to %d as one text[]", i, query.SQL, len(query.Vars), i*existingValuesChunk, end-1)
+				}
+				want[values[end-1]] = true
+			}
+			if !reflect.DeepEqual(existing, want) {
+				t.Errorf("existing = %v, want %v", existing, want)
+			}
+		})
+	}
+}
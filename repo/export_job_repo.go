@@ -0,0 +1,147 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"gorm.io/gorm"
+	"scylla/model"
+	"time"
+)
+
+type ExportJobRepo interface {
+	Insert(ctx context.Context, data model.ExportJob) error
+	FindById(ctx context.Context, Id string) (data model.ExportJob, err error)
+	FindQueued(ctx context.Context) (Id []string, err error)
+	FindExpired(ctx context.Context, now time.Time, instance string) (data []model.ExportJob, err error)
+	Claim(ctx context.Context, Id string, instance string) (bool, error)
+	Update(ctx context.Context, data model.ExportJob) error
+	UpdateProgress(ctx context.Context, Id string, rowsWritten int) error
+	Heartbeat(ctx context.Context, Id string) error
+	FailStale(ctx context.Context, message string, staleBefore time.Time, expiresAt time.Time) error
+}
+
+type ExportJobRepoImpl struct {
+	db *gorm.DB
+}
+
+func NewExportJobRepoImpl(db *gorm.DB) ExportJobRepo {
+	return &ExportJobRepoImpl{db: db}
+}
+
+func (repo *ExportJobRepoImpl) Insert(ctx context.Context, data model.ExportJob) error {
+	result := repo.db.WithContext(ctx).Create(&data)
+	if result.Error != nil {
+		return result.Error
+	}
+	return nil
+}
+
+func (repo *ExportJobRepoImpl) FindById(ctx context.Context, Id string) (data model.ExportJob, err error) {
+	result := repo.db.WithContext(ctx).Where("id = ?", Id).First(&data)
+	if result.RowsAffected == 0 {
+		return data, errors.New("record not found")
+	}
+
+	if result.Error != nil {
+		return data, result.Error
+	}
+
+	return data, nil
+}
+
+func (repo *ExportJobRepoImpl) FindQueued(ctx context.Context) (Id []string, err error) {
+	result := repo.db.WithContext(ctx).
+		Model(&model.ExportJob{}).
+		Where("state = ?", model.ExportJobQueued).
+		Order("created_at").
+		Pluck("id", &Id)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return Id, nil
+}
+
+// FindExpired returns the finished jobs due for removal that instance can clean up: the ones it
+// wrote the file for, those without a file and those from before jobs recorded their instance.
+func (repo *ExportJobRepoImpl) FindExpired(ctx context.Context, now time.Time, instance string) (data []model.ExportJob, err error) {
+	result := repo.db.WithContext(ctx).
+		Where("state IN ? AND expires_at < ?", []string{model.ExportJobCompleted, model.ExportJobFailed}, now).
+		Where("instance = ? OR instance IS NULL OR file_path IS NULL OR file_path = ''", instance).
+		Find(&data)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return data, nil
+}
+
+// Claim moves a queued job to running on instance and reports whether this caller won it, so a job
+// that is enqueued twice still only runs once.
+func (repo *ExportJobRepoImpl) Claim(ctx context.Context, Id string, instance string) (bool, error) {
+	result := repo.db.WithContext(ctx).
+		Model(&model.ExportJob{}).
+		Where("id = ? AND state = ?", Id, model.ExportJobQueued).
+		Updates(map[string]interface{}{"state": model.ExportJobRunning, "heartbeat_at": time.Now(), "instance": instance})
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected == 1, nil
+}
+
+func (repo *ExportJobRepoImpl) Update(ctx context.Context, data model.ExportJob) error {
+	result := repo.db.WithContext(ctx).
+		Model(&data).
+		Select("state", "total_rows", "rows_written", "file_path", "error", "expires_at", "finished_at").
+		Updates(&data)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	return nil
+}
+
+func (repo *ExportJobRepoImpl) UpdateProgress(ctx context.Context, Id string, rowsWritten int) error {
+	result := repo.db.WithContext(ctx).
+		Model(&model.ExportJob{}).
+		Where("id = ?", Id).
+		Update("rows_written", rowsWritten)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	return nil
+}
+
+// Heartbeat tells the other instances the worker running the job is still alive, see FailStale.
+func (repo *ExportJobRepoImpl) Heartbeat(ctx context.Context, Id string) error {
+	result := repo.db.WithContext(ctx).
+		Model(&model.ExportJob{}).
+		Where("id = ? AND state = ?", Id, model.ExportJobRunning).
+		Update("heartbeat_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+
+	return nil
+}
+
+// FailStale fails the running jobs without a heartbeat since staleBefore, their worker is gone
+// whichever instance it ran on. Jobs other instances are still running keep beating and stay.
+func (repo *ExportJobRepoImpl) FailStale(ctx context.Context, message string, staleBefore time.Time, expiresAt time.Time) error {
+	result := repo.db.WithContext(ctx).
+		Model(&model.ExportJob{}).
+		Where("state = ? AND (heartbeat_at IS NULL OR heartbeat_at < ?)", model.ExportJobRunning, staleBefore).
+		Updates(map[string]interface{}{
+			"state":       model.ExportJobFailed,
+			"error":       message,
+			"finished_at": time.Now(),
+			"expires_at":  expiresAt,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	return nil
+}
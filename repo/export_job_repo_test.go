@@ -0,0 +1,77 @@
+package repo
+
+import (
+	"context"
+	"scylla/repo/repotest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// lastWrite is the last statement before the COMMIT gorm wraps a write in.
+func lastWrite(t *testing.T, recorder *repotest.Driver) repotest.Query {
+	t.Helper()
+	for i := len(recorder.Queries) - 1; i >= 0; i-- {
+		if query := recorder.Queries[i]; query.SQL != "COMMIT" && query.SQL != "ROLLBACK" {
+			return query
+		}
+	}
+	t.Fatal("no statement was sent")
+	return repotest.Query{}
+}
+
+func TestClaimStartsTheLease(t *testing.T) {
+	db, recorder := repotest.Open(t)
+	recorder.RowsAffected = 1
+
+	claimed, err := NewExportJobRepoImpl(db).Claim(context.Background(), "job", "host-a")
+	if err != nil || !claimed {
+		t.Fatalf("Claim() = %v, %v, want the job claimed", claimed, err)
+	}
+
+	claim := lastWrite(t, recorder)
+	if !strings.Contains(claim.SQL, `"heartbeat_at"=`) || !strings.Contains(claim.SQL, `"instance"=`) || !strings.Contains(claim.SQL, "state = $") {
+		t.Errorf("claim = %q, want it to set heartbeat_at and instance on a queued job", claim.SQL)
+	}
+	found := false
+	for _, value := range claim.Vars {
+		found = found || value == "host-a"
+	}
+	if !found {
+		t.Errorf("vars = %v, want the instance among them", claim.Vars)
+	}
+}
+
+func TestFindExpiredLeavesFilesOfOtherInstances(t *testing.T) {
+	db, recorder := repotest.Open(t)
+
+	if _, err := NewExportJobRepoImpl(db).FindExpired(context.Background(), time.Now(), "host-a"); err != nil {
+		t.Fatalf("FindExpired() error = %v", err)
+	}
+
+	query := recorder.Queries[len(recorder.Queries)-1]
+	if !strings.Contains(query.SQL, "(instance = $") || query.Vars[len(query.Vars)-1] != "host-a" {
+		t.Errorf("find = %q %v, want it limited to the files of host-a", query.SQL, query.Vars)
+	}
+}
+
+func TestFailStaleOnlyTakesExpiredLeases(t *testing.T) {
+	db, recorder := repotest.Open(t)
+	staleBefore := time.Now().Add(-time.Minute)
+
+	if err := NewExportJobRepoImpl(db).FailStale(context.Background(), "gone", staleBefore, time.Now()); err != nil {
+		t.Fatalf("FailStale() error = %v", err)
+	}
+
+	query := lastWrite(t, recorder)
+	if !strings.Contains(query.SQL, "WHERE state = $") || !strings.Contains(query.SQL, "(heartbeat_at IS NULL OR heartbeat_at < $") {
+		t.Errorf("fail = %q, want it limited to running jobs without a recent heartbeat", query.SQL)
+	}
+	found := false
+	for _, value := range query.Vars {
+		found = found || value == staleBefore
+	}
+	if !found {
+		t.Errorf("vars = %v, want the lease bound among them", query.Vars)
+	}
+}
@@ -0,0 +1,93 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"gorm.io/gorm"
+	"scylla/model"
+	"time"
+)
+
+var ErrRefreshTokenReused = errors.New("refresh token has already been used")
+
+type RefreshTokenRepo interface {
+	Insert(ctx context.Context, data model.RefreshToken) error
+	FindByHash(ctx context.Context, hash string) (data model.RefreshToken, err error)
+	Rotate(ctx context.Context, oldId int, data model.RefreshToken) error
+	RevokeFamily(ctx context.Context, familyId string) error
+}
+
+type RefreshTokenRepoImpl struct {
+	db *gorm.DB
+}
+
+func NewRefreshTokenRepoImpl(db *gorm.DB) RefreshTokenRepo {
+	return &RefreshTokenRepoImpl{db: db}
+}
+
+func (repo *RefreshTokenRepoImpl) Insert(ctx context.Context, data model.RefreshToken) error {
+	result := repo.db.WithContext(ctx).Create(&data)
+	if result.Error != nil {
+		return result.Error
+	}
+	return nil
+}
+
+func (repo *RefreshTokenRepoImpl) FindByHash(ctx context.Context, hash string) (data model.RefreshToken, err error) {
+	result := repo.db.WithContext(ctx).Where("token_hash = ?", hash).First(&data)
+	if result.RowsAffected == 0 {
+		return data, errors.New("record not found")
+	}
+
+	if result.Error != nil {
+		return data, result.Error
+	}
+
+	return data, nil
+}
+
+// Rotate stores the successor token and revokes the old one in a single transaction.
+// The revoke only matches a still-active row, so two concurrent refreshes with the
+// same token cannot both succeed: the loser gets ErrRefreshTokenReused.
+func (repo *RefreshTokenRepoImpl) Rotate(ctx context.Context, oldId int, data model.RefreshToken) error {
+	tx := repo.db.WithContext(ctx).Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+
+	if err := tx.Create(&data).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	result := tx.Model(&model.RefreshToken{}).
+		Where("id = ? AND revoked_at IS NULL", oldId).
+		Updates(map[string]interface{}{"revoked_at": time.Now(), "replaced_by": data.ID})
+	if result.Error != nil {
+		tx.Rollback()
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		tx.Rollback()
+		return ErrRefreshTokenReused
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (repo *RefreshTokenRepoImpl) RevokeFamily(ctx context.Context, familyId string) error {
+	result := repo.db.WithContext(ctx).
+		Model(&model.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyId).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+
+	return nil
+}
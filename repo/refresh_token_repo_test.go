@@ -0,0 +1,64 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"scylla/model"
+	"scylla/repo/repotest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRotate(t *testing.T) {
+	tests := []struct {
+		name         string
+		rowsAffected int64
+		err          error
+		end          string
+	}{
+		{"active token", 1, nil, "COMMIT"},
+		{"already revoked", 0, ErrRefreshTokenReused, "ROLLBACK"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, recorder := repotest.Open(t)
+			recorder.RowsAffected = test.rowsAffected
+			tokenRepo := NewRefreshTokenRepoImpl(db)
+
+			err := tokenRepo.Rotate(context.Background(), 3, model.RefreshToken{UserID: 1, TokenHash: "hash", FamilyID: "family", ExpiresAt: time.Now()})
+			if !errors.Is(err, test.err) {
+				t.Fatalf("Rotate() error = %v, want %v", err, test.err)
+			}
+
+			var statements []string
+			for _, query := range recorder.Queries {
+				statements = append(statements, query.SQL)
+			}
+			if len(statements) != 3 || !strings.HasPrefix(statements[0], "INSERT INTO \"refresh_tokens\"") {
+				t.Fatalf("statements = %q, want the insert, the revoke and the end of the transaction", statements)
+			}
+			// only a token nobody rotated yet may be revoked, that is what catches a second use
+			if revoke := statements[1]; !strings.HasPrefix(revoke, "UPDATE \"refresh_tokens\"") || !strings.Contains(revoke, "id = $") || !strings.Contains(revoke, "revoked_at IS NULL") {
+				t.Errorf("revoke = %q, want it limited to the active old token", revoke)
+			}
+			if statements[2] != test.end {
+				t.Errorf("transaction ended with %q, want %q", statements[2], test.end)
+			}
+		})
+	}
+}
+
+func TestRevokeFamily(t *testing.T) {
+	db, recorder := repotest.Open(t)
+	if err := NewRefreshTokenRepoImpl(db).RevokeFamily(context.Background(), "family"); err != nil {
+		t.Fatalf("RevokeFamily() error = %v", err)
+	}
+
+	// gorm wraps the update in a transaction of its own
+	revoke := recorder.Queries[len(recorder.Queries)-2]
+	if !strings.Contains(revoke.SQL, "family_id = $") || !strings.Contains(revoke.SQL, "revoked_at IS NULL") || revoke.Vars[len(revoke.Vars)-1] != "family" {
+		t.Errorf("revoke = %q %v, want every active token of the family", revoke.SQL, revoke.Vars)
+	}
+}
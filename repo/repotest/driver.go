@@ -0,0 +1,132 @@
+// Package repotest runs the repositories on a database/sql driver that records the statements
+// instead of sending them anywhere, so tests can check the SQL without a database.
+package repotest
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"io"
+	"sync"
+	"testing"
+)
+
+// Query is a statement sent to the Driver.
+type Query struct {
+	SQL  string
+	Vars []interface{}
+}
+
+// Driver keeps every statement and answers queries with no rows unless Rows says otherwise.
+// Transactions show up as a COMMIT or ROLLBACK statement.
+type Driver struct {
+	Queries []Query
+	// RowsAffected is what every exec statement reports
+	RowsAffected int64
+	// QueryErr fails every query when set
+	QueryErr error
+	// ExecErr fails every exec statement when set
+	ExecErr error
+	// Rows answers a query with its columns and rows, nil answers every query with no rows
+	Rows func(query string, vars []interface{}) (columns []string, rows [][]driver.Value)
+}
+
+func (recorder *Driver) record(query string, args []driver.NamedValue) {
+	vars := make([]interface{}, len(args))
+	for i, arg := range args {
+		vars[i] = arg.Value
+	}
+	recorder.Queries = append(recorder.Queries, Query{SQL: query, Vars: vars})
+}
+
+func (recorder *Driver) Open(name string) (driver.Conn, error) {
+	return &conn{recorder: recorder}, nil
+}
+
+type conn struct {
+	recorder *Driver
+}
+
+func (conn *conn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("prepare is not supported")
+}
+
+func (conn *conn) Close() error { return nil }
+
+func (conn *conn) Begin() (driver.Tx, error) {
+	return tx{recorder: conn.recorder}, nil
+}
+
+func (conn *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	conn.recorder.record(query, args)
+	if conn.recorder.QueryErr != nil {
+		return nil, conn.recorder.QueryErr
+	}
+	if conn.recorder.Rows == nil {
+		return &rows{}, nil
+	}
+	columns, values := conn.recorder.Rows(query, conn.recorder.Queries[len(conn.recorder.Queries)-1].Vars)
+	return &rows{columns: columns, values: values}, nil
+}
+
+func (conn *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	conn.recorder.record(query, args)
+	if conn.recorder.ExecErr != nil {
+		return nil, conn.recorder.ExecErr
+	}
+	return driver.RowsAffected(conn.recorder.RowsAffected), nil
+}
+
+type tx struct {
+	recorder *Driver
+}
+
+func (tx tx) Commit() error {
+	tx.recorder.record("COMMIT", nil)
+	return nil
+}
+
+func (tx tx) Rollback() error {
+	tx.recorder.record("ROLLBACK", nil)
+	return nil
+}
+
+type rows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (rows *rows) Columns() []string { return rows.columns }
+func (rows *rows) Close() error      { return nil }
+
+func (rows *rows) Next(dest []driver.Value) error {
+	if len(rows.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, rows.values[0])
+	rows.values = rows.values[1:]
+	return nil
+}
+
+var register sync.Once
+var recorder = &Driver{}
+
+// Open returns a gorm database on the recording driver, with the driver reset.
+func Open(t *testing.T) (*gorm.DB, *Driver) {
+	register.Do(func() { sql.Register("recorder", recorder) })
+	*recorder = Driver{}
+
+	conn, err := sql.Open("recorder", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{DisableAutomaticPing: true, Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db, recorder
+}
@@ -0,0 +1,63 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"gorm.io/gorm"
+	"scylla/model"
+)
+
+type UserRepo interface {
+	FindById(ctx context.Context, Id int) (data model.User, err error)
+	FindByUsername(ctx context.Context, username string) (data model.User, err error)
+	InsertWithRole(ctx context.Context, data model.User, role string) (created bool, err error)
+}
+
+type UserRepoImpl struct {
+	db *gorm.DB
+}
+
+func NewUserRepoImpl(db *gorm.DB) UserRepo {
+	return &UserRepoImpl{db: db}
+}
+
+func (repo *UserRepoImpl) FindById(ctx context.Context, Id int) (data model.User, err error) {
+	result := repo.db.WithContext(ctx).Preload("Role.Permissions").First(&data, Id)
+	if result.RowsAffected == 0 {
+		return data, errors.New("record not found")
+	}
+
+	if result.Error != nil {
+		return data, result.Error
+	}
+
+	return data, nil
+}
+
+func (repo *UserRepoImpl) FindByUsername(ctx context.Context, username string) (data model.User, err error) {
+	result := repo.db.WithContext(ctx).Preload("Role.Permissions").Where("username = ?", username).First(&data)
+	if result.RowsAffected == 0 {
+		return data, errors.New("record not found")
+	}
+
+	if result.Error != nil {
+		return data, result.Error
+	}
+
+	return data, nil
+}
+
+// InsertWithRole creates the user with the role named role. A taken username, or a role that does
+// not exist, leaves everything as it was and reports created false.
+func (repo *UserRepoImpl) InsertWithRole(ctx context.Context, data model.User, role string) (created bool, err error) {
+	result := repo.db.WithContext(ctx).Exec(`
+		INSERT INTO users (username, email, password, role_id)
+		SELECT ?, NULLIF(?, ''), ?, id FROM roles WHERE name = ?
+		ON CONFLICT (username) DO NOTHING`,
+		data.Username, data.Email, data.Password, role)
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected > 0, nil
+}
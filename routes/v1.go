@@ -3,22 +3,41 @@ package routes
 import (
 	"github.com/labstack/echo/v4"
 	"scylla/handler"
+	"scylla/pkg/middlewares"
 )
 
 func NewRoutesV1(
 	app *echo.Echo,
+	jwtMiddleware echo.MiddlewareFunc,
+	authHandler *handler.AuthHandler,
 	customerHandler *handler.CustomerHandler,
+	exportJobHandler *handler.ExportJobHandler,
 ) {
 	routes := app.Group("/api/v1")
+	//auth
+	authRouter := routes.Group("/auth")
+	authRouter.POST("/login", authHandler.Login)
+	authRouter.POST("/refresh", authHandler.Refresh)
+	authRouter.POST("/logout", authHandler.Logout)
+
 	//customer
-	customerRouter := routes.Group("/customers")
-	customerRouter.GET("", customerHandler.FindAllPaging)
-	customerRouter.GET("/:customerId", customerHandler.FindById)
-	customerRouter.GET("/export", customerHandler.Export)
-	customerRouter.POST("/import", customerHandler.Import)
-	customerRouter.POST("", customerHandler.Create)
-	customerRouter.POST("/batch", customerHandler.CreateBatch)
-	customerRouter.PATCH("/:customerId", customerHandler.Update)
-	customerRouter.DELETE("/batch", customerHandler.DeleteBatch)
+	customerRouter := routes.Group("/customers", jwtMiddleware)
+	customerRouter.GET("", customerHandler.FindAllPaging, middlewares.RequirePermission("customers:read"))
+	customerRouter.GET("/:customerId", customerHandler.FindById, middlewares.RequirePermission("customers:read"))
+	customerRouter.GET("/export", customerHandler.Export, middlewares.RequirePermission("customers:read"))
+	customerRouter.POST("/exports", exportJobHandler.Create, middlewares.RequirePermission("customers:read"))
+	customerRouter.GET("/exports/:exportId", exportJobHandler.FindById, middlewares.RequirePermission("customers:read"))
+	customerRouter.GET("/exports/:exportId/download", exportJobHandler.Download, middlewares.RequirePermission("customers:read"))
+	customerRouter.POST("/lookup", customerHandler.Lookup, middlewares.RequirePermission("customers:read"))
+	customerRouter.GET("/import/template", customerHandler.ImportTemplate, middlewares.RequirePermission("customers:import"))
+	customerRouter.POST("/import", customerHandler.Import, middlewares.RequirePermission("customers:import"))
+	customerRouter.POST("", customerHandler.Create, middlewares.RequirePermission("customers:write"))
+	customerRouter.POST("/batch", customerHandler.CreateBatch, middlewares.RequirePermission("customers:write"))
+	customerRouter.PATCH("/batch", customerHandler.BulkUpdate, middlewares.RequirePermission("customers:write"))
+	customerRouter.PUT("/:customerId", customerHandler.Update, middlewares.RequirePermission("customers:write"))
+	customerRouter.PATCH("/:customerId", customerHandler.Patch, middlewares.RequirePermission("customers:write"))
+	customerRouter.DELETE("/batch", customerHandler.DeleteBatch, middlewares.RequirePermission("customers:delete"))
+	customerRouter.POST("/restore", customerHandler.RestoreBatch, middlewares.RequirePermission("customers:delete"))
+	customerRouter.DELETE("/purge", customerHandler.PurgeBatch, middlewares.RequirePermission("customers:purge"))
 
 }
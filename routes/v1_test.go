@@ -0,0 +1,177 @@
+package routes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"scylla/handler"
+	"scylla/pkg/exception"
+	"scylla/pkg/middlewares"
+	"scylla/pkg/token"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+)
+
+// customerRoutes is every customer route with the permission it requires.
+var customerRoutes = []struct {
+	method     string
+	path       string
+	permission string
+}{
+	{http.MethodGet, "/api/v1/customers", "customers:read"},
+	{http.MethodGet, "/api/v1/customers/:customerId", "customers:read"},
+	{http.MethodGet, "/api/v1/customers/export", "customers:read"},
+	{http.MethodPost, "/api/v1/customers/exports", "customers:read"},
+	{http.MethodGet, "/api/v1/customers/exports/:exportId", "customers:read"},
+	{http.MethodGet, "/api/v1/customers/exports/:exportId/download", "customers:read"},
+	{http.MethodPost, "/api/v1/customers/lookup", "customers:read"},
+	{http.MethodGet, "/api/v1/customers/import/template", "customers:import"},
+	{http.MethodPost, "/api/v1/customers/import", "customers:import"},
+	{http.MethodPost, "/api/v1/customers", "customers:write"},
+	{http.MethodPost, "/api/v1/customers/batch", "customers:write"},
+	{http.MethodPatch, "/api/v1/customers/batch", "customers:write"},
+	{http.MethodPut, "/api/v1/customers/:customerId", "customers:write"},
+	{http.MethodPatch, "/api/v1/customers/:customerId", "customers:write"},
+	{http.MethodDelete, "/api/v1/customers/batch", "customers:delete"},
+	{http.MethodPost, "/api/v1/customers/restore", "customers:delete"},
+	{http.MethodDelete, "/api/v1/customers/purge", "customers:purge"},
+}
+
+// testServer mounts the v1 routes with a JWT middleware that grants the comma separated
+// permissions of the X-Permissions header. The handlers have no usecases, a request that gets
+// past the permission check ends in a recovered panic or a validation error.
+func testServer() *echo.Echo {
+	app := echo.New()
+	app.HTTPErrorHandler = exception.ExceptionHandlers
+	app.Use(middleware.RecoverWithConfig(middleware.RecoverConfig{DisablePrintStack: true}))
+
+	jwt := func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			claims := &token.Claims{Username: "tester"}
+			if header := c.Request().Header.Get("X-Permissions"); header != "" {
+				claims.Permissions = strings.Split(header, ",")
+			}
+			c.Set(middlewares.ClaimsContextKey, claims)
+			return next(c)
+		}
+	}
+
+	NewRoutesV1(app, jwt, handler.NewAuthHandler(nil), handler.NewCustomerHandler(nil), handler.NewExportJobHandler(nil))
+	return app
+}
+
+func serve(app *echo.Echo, method string, path string, permissions []string) int {
+	path = strings.NewReplacer(":customerId", "1", ":exportId", "1").Replace(path)
+	request := httptest.NewRequest(method, path, nil)
+	request.Header.Set("X-Permissions", strings.Join(permissions, ","))
+	recorder := httptest.NewRecorder()
+	app.ServeHTTP(recorder, request)
+	return recorder.Code
+}
+
+// migrationRoles reads the permissions of each role out of the migrations: admin holds every
+// permission, ops the list its role_permissions insert names.
+func migrationRoles(t *testing.T) map[string][]string {
+	t.Helper()
+	files, err := filepath.Glob("../pkg/migrations/*.up.sql")
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no migrations found: %v", err)
+	}
+
+	var sql string
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sql += string(content)
+	}
+
+	permission := regexp.MustCompile(`'(customers:[a-z]+)'`)
+	all := map[string]bool{}
+	for _, match := range permission.FindAllStringSubmatch(sql, -1) {
+		all[match[1]] = true
+	}
+	roles := map[string][]string{}
+	for name := range all {
+		roles["admin"] = append(roles["admin"], name)
+	}
+	sort.Strings(roles["admin"])
+
+	ops := regexp.MustCompile(`r\.name = 'ops' AND p\.name IN \(([^)]*)\)`).FindStringSubmatch(sql)
+	if ops == nil {
+		t.Fatal("the ops grant was not found in the migrations")
+	}
+	for _, match := range permission.FindAllStringSubmatch(ops[1], -1) {
+		roles["ops"] = append(roles["ops"], match[1])
+	}
+	return roles
+}
+
+func TestCustomerRoutesAreListed(t *testing.T) {
+	listed := map[string]bool{}
+	for _, route := range customerRoutes {
+		listed[route.method+" "+route.path] = true
+	}
+
+	for _, route := range testServer().Routes() {
+		// the group registers catch-all routes of its own so its middleware also runs on a 404
+		if route.Method == echo.RouteNotFound {
+			continue
+		}
+		if strings.HasPrefix(route.Path, "/api/v1/customers") && !listed[route.Method+" "+route.Path] {
+			t.Errorf("%s %s is missing from customerRoutes", route.Method, route.Path)
+		}
+	}
+}
+
+func TestCustomerRoutesRequirePermission(t *testing.T) {
+	app := testServer()
+
+	for _, route := range customerRoutes {
+		t.Run(route.method+" "+route.path, func(t *testing.T) {
+			// every other permission is not enough
+			var others []string
+			for _, permission := range []string{"customers:read", "customers:write", "customers:delete", "customers:purge", "customers:import"} {
+				if permission != route.permission {
+					others = append(others, permission)
+				}
+			}
+			if status := serve(app, route.method, route.path, others); status != http.StatusForbidden {
+				t.Errorf("without %s status = %d, want 403", route.permission, status)
+			}
+
+			if status := serve(app, route.method, route.path, []string{route.permission}); status == http.StatusForbidden || status == http.StatusUnauthorized {
+				t.Errorf("with %s status = %d, want the request let through", route.permission, status)
+			}
+		})
+	}
+}
+
+func TestRolesFromMigrations(t *testing.T) {
+	app := testServer()
+	roles := migrationRoles(t)
+
+	if want := []string{"customers:delete", "customers:import", "customers:purge", "customers:read", "customers:write"}; strings.Join(roles["admin"], ",") != strings.Join(want, ",") {
+		t.Errorf("admin permissions = %v, want %v", roles["admin"], want)
+	}
+
+	denied := map[string]map[string]bool{
+		"admin": {},
+		"ops":   {"customers:delete": true, "customers:purge": true, "customers:import": true},
+	}
+	for role, permissions := range roles {
+		for _, route := range customerRoutes {
+			status := serve(app, route.method, route.path, permissions)
+			if forbidden := status == http.StatusForbidden; forbidden != denied[role][route.permission] {
+				t.Errorf("%s %s %s status = %d, want denied = %v", role, route.method, route.path, status, denied[role][route.permission])
+			}
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"scylla/entity"
+	"scylla/model"
+	"scylla/pkg/exception"
+	"scylla/pkg/helper"
+	"scylla/pkg/token"
+	"scylla/repo"
+	"time"
+)
+
+type AuthUsecase interface {
+	Login(ctx context.Context, request entity.LoginRequest) (response entity.TokenResponse)
+	Refresh(ctx context.Context, request entity.RefreshTokenRequest) (response entity.TokenResponse)
+	Logout(ctx context.Context, request entity.RefreshTokenRequest)
+	CreateUser(ctx context.Context, request entity.CreateUserRequest) (created bool)
+}
+
+// comparePasswordDummy is swapped out by the tests to see that an unknown username still pays
+// for a bcrypt compare.
+var comparePasswordDummy = helper.ComparePasswordDummy
+
+type AuthUsecaseImpl struct {
+	userRepo         repo.UserRepo
+	refreshTokenRepo repo.RefreshTokenRepo
+	tokenManager     *token.Manager
+	validate         *validator.Validate
+}
+
+func NewAuthUsecaseImpl(userRepo repo.UserRepo, refreshTokenRepo repo.RefreshTokenRepo, tokenManager *token.Manager, validate *validator.Validate) AuthUsecase {
+	return &AuthUsecaseImpl{
+		userRepo:         userRepo,
+		refreshTokenRepo: refreshTokenRepo,
+		tokenManager:     tokenManager,
+		validate:         validate,
+	}
+}
+
+func (usecase *AuthUsecaseImpl) Login(ctx context.Context, request entity.LoginRequest) (response entity.TokenResponse) {
+	err := usecase.validate.Struct(request)
+	helper.ErrorPanic(err)
+
+	user, err := usecase.userRepo.FindByUsername(ctx, request.Username)
+	if err != nil {
+		// still pay for a bcrypt compare, or the response time tells which usernames exist
+		comparePasswordDummy(request.Password)
+		panic(exception.NewUnauthorizedHandler("invalid username or password"))
+	}
+	if !helper.ComparePassword(user.Password, request.Password) {
+		panic(exception.NewUnauthorizedHandler("invalid username or password"))
+	}
+
+	refreshToken, hash, expiresAt, err := usecase.tokenManager.GenerateRefreshToken()
+	if err != nil {
+		panic(exception.NewInternalServerErrorHandler(err.Error()))
+	}
+
+	// every login starts a new token family, rotations inherit it
+	err = usecase.refreshTokenRepo.Insert(ctx, model.RefreshToken{
+		UserID:    user.ID,
+		TokenHash: hash,
+		FamilyID:  uuid.New().String(),
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		panic(exception.NewInternalServerErrorHandler(err.Error()))
+	}
+
+	return usecase.tokenResponse(user, refreshToken)
+}
+
+func (usecase *AuthUsecaseImpl) Refresh(ctx context.Context, request entity.RefreshTokenRequest) (response entity.TokenResponse) {
+	err := usecase.validate.Struct(request)
+	helper.ErrorPanic(err)
+
+	current, err := usecase.refreshTokenRepo.FindByHash(ctx, token.HashRefreshToken(request.RefreshToken))
+	if err != nil {
+		panic(exception.NewUnauthorizedHandler("invalid refresh token"))
+	}
+
+	// a revoked token being presented again means it leaked, so kill the whole session
+	if current.RevokedAt != nil {
+		usecase.revokeFamily(ctx, current.FamilyID)
+		panic(exception.NewUnauthorizedHandler(repo.ErrRefreshTokenReused.Error()))
+	}
+
+	if time.Now().After(current.ExpiresAt) {
+		panic(exception.NewUnauthorizedHandler("refresh token expired"))
+	}
+
+	user, err := usecase.userRepo.FindById(ctx, current.UserID)
+	if err != nil {
+		panic(exception.NewUnauthorizedHandler("invalid refresh token"))
+	}
+
+	refreshToken, hash, expiresAt, err := usecase.tokenManager.GenerateRefreshToken()
+	if err != nil {
+		panic(exception.NewInternalServerErrorHandler(err.Error()))
+	}
+
+	err = usecase.refreshTokenRepo.Rotate(ctx, current.ID, model.RefreshToken{
+		UserID:    user.ID,
+		TokenHash: hash,
+		FamilyID:  current.FamilyID,
+		ExpiresAt: expiresAt,
+	})
+	if errors.Is(err, repo.ErrRefreshTokenReused) {
+		usecase.revokeFamily(ctx, current.FamilyID)
+		panic(exception.NewUnauthorizedHandler(err.Error()))
+	}
+	if err != nil {
+		panic(exception.NewInternalServerErrorHandler(err.Error()))
+	}
+
+	return usecase.tokenResponse(user, refreshToken)
+}
+
+func (usecase *AuthUsecaseImpl) Logout(ctx context.Context, request entity.RefreshTokenRequest) {
+	err := usecase.validate.Struct(request)
+	helper.ErrorPanic(err)
+
+	current, err := usecase.refreshTokenRepo.FindByHash(ctx, token.HashRefreshToken(request.RefreshToken))
+	if err != nil {
+		panic(exception.NewUnauthorizedHandler("invalid refresh token"))
+	}
+
+	usecase.revokeFamily(ctx, current.FamilyID)
+}
+
+// CreateUser stores the user with a bcrypt hash of its password. An existing username is left
+// untouched, its password is never reset from here.
+func (usecase *AuthUsecaseImpl) CreateUser(ctx context.Context, request entity.CreateUserRequest) (created bool) {
+	err := usecase.validate.Struct(request)
+	helper.ErrorPanic(err)
+
+	hashed, err := helper.HashPassword(request.Password)
+	if err != nil {
+		panic(exception.NewInternalServerErrorHandler(err.Error()))
+	}
+
+	created, err = usecase.userRepo.InsertWithRole(ctx, model.User{
+		Username: request.Username,
+		Email:    request.Email,
+		Password: hashed,
+	}, request.Role)
+	if err != nil {
+		panic(exception.NewInternalServerErrorHandler(err.Error()))
+	}
+
+	return created
+}
+
+func (usecase *AuthUsecaseImpl) revokeFamily(ctx context.Context, familyId string) {
+	err := usecase.refreshTokenRepo.RevokeFamily(ctx, familyId)
+	if err != nil {
+		panic(exception.NewInternalServerErrorHandler(err.Error()))
+	}
+}
+
+func (usecase *AuthUsecaseImpl) tokenResponse(user model.User, refreshToken string) entity.TokenResponse {
+	var role string
+	var permissions []string
+	if user.Role != nil {
+		role = user.Role.Name
+		for _, permission := range user.Role.Permissions {
+			permissions = append(permissions, permission.Name)
+		}
+	}
+
+	accessToken, expiresAt, err := usecase.tokenManager.GenerateAccessToken(user.ID, user.Username, role, permissions)
+	if err != nil {
+		panic(exception.NewInternalServerErrorHandler(err.Error()))
+	}
+
+	return entity.TokenResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(time.Until(expiresAt).Seconds()),
+	}
+}
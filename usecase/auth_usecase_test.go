@@ -0,0 +1,245 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"scylla/entity"
+	"scylla/model"
+	"scylla/pkg/config"
+	"scylla/pkg/exception"
+	"scylla/pkg/helper"
+	"scylla/pkg/token"
+	"scylla/repo"
+	"testing"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// fakeUserRepo knows a single user.
+type fakeUserRepo struct {
+	user model.User
+}
+
+func (users *fakeUserRepo) FindById(ctx context.Context, Id int) (model.User, error) {
+	if Id != users.user.ID {
+		return model.User{}, errors.New("record not found")
+	}
+	return users.user, nil
+}
+
+func (users *fakeUserRepo) FindByUsername(ctx context.Context, username string) (model.User, error) {
+	if username != users.user.Username {
+		return model.User{}, errors.New("record not found")
+	}
+	return users.user, nil
+}
+
+func (users *fakeUserRepo) InsertWithRole(ctx context.Context, data model.User, role string) (bool, error) {
+	return false, errors.New("not supported")
+}
+
+// fakeRefreshTokenRepo keeps the tokens in memory with the revoke rules of RefreshTokenRepoImpl.
+type fakeRefreshTokenRepo struct {
+	tokens []model.RefreshToken
+	// lostRace makes Rotate behave as if a concurrent refresh revoked the old token first
+	lostRace bool
+}
+
+func (tokens *fakeRefreshTokenRepo) Insert(ctx context.Context, data model.RefreshToken) error {
+	data.ID = len(tokens.tokens) + 1
+	tokens.tokens = append(tokens.tokens, data)
+	return nil
+}
+
+func (tokens *fakeRefreshTokenRepo) FindByHash(ctx context.Context, hash string) (model.RefreshToken, error) {
+	for _, data := range tokens.tokens {
+		if data.TokenHash == hash {
+			return data, nil
+		}
+	}
+	return model.RefreshToken{}, errors.New("record not found")
+}
+
+func (tokens *fakeRefreshTokenRepo) Rotate(ctx context.Context, oldId int, data model.RefreshToken) error {
+	if tokens.lostRace || tokens.tokens[oldId-1].RevokedAt != nil {
+		return repo.ErrRefreshTokenReused
+	}
+
+	if err := tokens.Insert(ctx, data); err != nil {
+		return err
+	}
+	now, replacedBy := time.Now(), len(tokens.tokens)
+	tokens.tokens[oldId-1].RevokedAt, tokens.tokens[oldId-1].ReplacedBy = &now, &replacedBy
+	return nil
+}
+
+func (tokens *fakeRefreshTokenRepo) RevokeFamily(ctx context.Context, familyId string) error {
+	now := time.Now()
+	for i := range tokens.tokens {
+		if tokens.tokens[i].FamilyID == familyId && tokens.tokens[i].RevokedAt == nil {
+			tokens.tokens[i].RevokedAt = &now
+		}
+	}
+	return nil
+}
+
+// active counts the tokens of familyId that are not revoked.
+func (tokens *fakeRefreshTokenRepo) active(familyId string) int {
+	count := 0
+	for _, data := range tokens.tokens {
+		if data.FamilyID == familyId && data.RevokedAt == nil {
+			count++
+		}
+	}
+	return count
+}
+
+const testPassword = "correct horse battery"
+
+func newTestAuthUsecase(t *testing.T) (AuthUsecase, *fakeRefreshTokenRepo, *token.Manager) {
+	t.Helper()
+	manager, err := token.NewManager(&config.Config{
+		JwtSecretKey:       "a-secret-long-enough-for-hs256",
+		JwtAccessTokenTTL:  time.Minute,
+		JwtRefreshTokenTTL: time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hashed, err := helper.HashPassword(testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	users := &fakeUserRepo{user: model.User{ID: 1, Username: "admin", Password: hashed}}
+	tokens := &fakeRefreshTokenRepo{}
+	return NewAuthUsecaseImpl(users, tokens, manager, validator.New()), tokens, manager
+}
+
+// unauthorized runs fn and returns the message of the 401 it panics with.
+func unauthorized(t *testing.T, fn func()) (message string) {
+	t.Helper()
+	defer func() {
+		exception, ok := recover().(*exception.UnauthorizedStruct)
+		if !ok {
+			t.Fatal("did not panic with an unauthorized error")
+		}
+		message = exception.Error()
+	}()
+	fn()
+	return ""
+}
+
+func TestRefreshRotates(t *testing.T) {
+	auth, tokens, manager := newTestAuthUsecase(t)
+	ctx := context.Background()
+
+	login := auth.Login(ctx, entity.LoginRequest{Username: "admin", Password: testPassword})
+	refreshed := auth.Refresh(ctx, entity.RefreshTokenRequest{RefreshToken: login.RefreshToken})
+
+	if refreshed.RefreshToken == login.RefreshToken {
+		t.Error("the refresh token was not rotated")
+	}
+	if _, err := manager.Parse(refreshed.AccessToken); err != nil {
+		t.Errorf("refreshed access token: %v", err)
+	}
+
+	old, successor := tokens.tokens[0], tokens.tokens[1]
+	if old.RevokedAt == nil || old.ReplacedBy == nil || *old.ReplacedBy != successor.ID {
+		t.Errorf("old token = %+v, want it revoked and replaced by %d", old, successor.ID)
+	}
+	if successor.FamilyID != old.FamilyID || successor.TokenHash != token.HashRefreshToken(refreshed.RefreshToken) {
+		t.Errorf("successor = %+v, want the family %s and the hash of the new token", successor, old.FamilyID)
+	}
+	if active := tokens.active(old.FamilyID); active != 1 {
+		t.Errorf("%d active tokens in the family, want 1", active)
+	}
+}
+
+func TestRefreshReuseRevokesFamily(t *testing.T) {
+	auth, tokens, _ := newTestAuthUsecase(t)
+	ctx := context.Background()
+
+	login := auth.Login(ctx, entity.LoginRequest{Username: "admin", Password: testPassword})
+	refreshed := auth.Refresh(ctx, entity.RefreshTokenRequest{RefreshToken: login.RefreshToken})
+
+	message := unauthorized(t, func() { auth.Refresh(ctx, entity.RefreshTokenRequest{RefreshToken: login.RefreshToken}) })
+	if message != repo.ErrRefreshTokenReused.Error() {
+		t.Errorf("message = %q, want %q", message, repo.ErrRefreshTokenReused.Error())
+	}
+	if active := tokens.active(tokens.tokens[0].FamilyID); active != 0 {
+		t.Errorf("%d active tokens left in the family, want it revoked", active)
+	}
+
+	// the legitimate successor dies with the family
+	unauthorized(t, func() { auth.Refresh(ctx, entity.RefreshTokenRequest{RefreshToken: refreshed.RefreshToken}) })
+}
+
+func TestRefreshLostRaceRevokesFamily(t *testing.T) {
+	auth, tokens, _ := newTestAuthUsecase(t)
+	ctx := context.Background()
+
+	login := auth.Login(ctx, entity.LoginRequest{Username: "admin", Password: testPassword})
+	tokens.lostRace = true
+
+	unauthorized(t, func() { auth.Refresh(ctx, entity.RefreshTokenRequest{RefreshToken: login.RefreshToken}) })
+	if active := tokens.active(tokens.tokens[0].FamilyID); active != 0 {
+		t.Errorf("%d active tokens left in the family, want it revoked", active)
+	}
+}
+
+func TestRefreshExpired(t *testing.T) {
+	auth, tokens, _ := newTestAuthUsecase(t)
+	ctx := context.Background()
+
+	login := auth.Login(ctx, entity.LoginRequest{Username: "admin", Password: testPassword})
+	tokens.tokens[0].ExpiresAt = time.Now().Add(-time.Second)
+
+	if message := unauthorized(t, func() { auth.Refresh(ctx, entity.RefreshTokenRequest{RefreshToken: login.RefreshToken}) }); message != "refresh token expired" {
+		t.Errorf("message = %q, want refresh token expired", message)
+	}
+}
+
+func TestLogoutRevokesFamily(t *testing.T) {
+	auth, tokens, _ := newTestAuthUsecase(t)
+	ctx := context.Background()
+
+	first := auth.Login(ctx, entity.LoginRequest{Username: "admin", Password: testPassword})
+	refreshed := auth.Refresh(ctx, entity.RefreshTokenRequest{RefreshToken: first.RefreshToken})
+	other := auth.Login(ctx, entity.LoginRequest{Username: "admin", Password: testPassword})
+
+	auth.Logout(ctx, entity.RefreshTokenRequest{RefreshToken: refreshed.RefreshToken})
+
+	if active := tokens.active(tokens.tokens[0].FamilyID); active != 0 {
+		t.Errorf("%d active tokens left in the family, want it revoked", active)
+	}
+	unauthorized(t, func() { auth.Refresh(ctx, entity.RefreshTokenRequest{RefreshToken: refreshed.RefreshToken}) })
+
+	// another login is another session and survives the logout
+	auth.Refresh(ctx, entity.RefreshTokenRequest{RefreshToken: other.RefreshToken})
+}
+
+func TestLoginComparesDummyForUnknownUser(t *testing.T) {
+	auth, _, _ := newTestAuthUsecase(t)
+	ctx := context.Background()
+
+	var dummyCompares []string
+	comparePasswordDummy = func(password string) { dummyCompares = append(dummyCompares, password) }
+	t.Cleanup(func() { comparePasswordDummy = helper.ComparePasswordDummy })
+
+	unknown := unauthorized(t, func() { auth.Login(ctx, entity.LoginRequest{Username: "nobody", Password: "guess"}) })
+	if len(dummyCompares) != 1 || dummyCompares[0] != "guess" {
+		t.Errorf("dummy compares = %q, want one with the given password", dummyCompares)
+	}
+
+	wrong := unauthorized(t, func() { auth.Login(ctx, entity.LoginRequest{Username: "admin", Password: "guess"}) })
+	if len(dummyCompares) != 1 {
+		t.Error("a known user went through the dummy compare")
+	}
+
+	// nor may the message tell the two apart
+	if unknown != wrong {
+		t.Errorf("unknown user says %q, wrong password says %q", unknown, wrong)
+	}
+}
@@ -2,30 +2,44 @@ package usecase
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	jsonpatch "github.com/evanphx/json-patch/v5"
 	"github.com/go-playground/validator/v10"
 	"github.com/xuri/excelize/v2"
-	"golang.org/x/sync/errgroup"
+	"io"
 	"math"
+	"reflect"
 	"scylla/entity"
 	"scylla/model"
 	"scylla/pkg/exception"
 	"scylla/pkg/helper"
+	"scylla/pkg/tabular"
+	"scylla/pkg/utils"
 	"scylla/repo"
-	"strings"
-	"time"
+	"sort"
 )
 
 type CustomerUsecase interface {
 	Create(ctx context.Context, request entity.CreateCustomerRequest)
-	CreateBatch(ctx context.Context, request entity.CreateCustomerBatchRequest)
-	Update(ctx context.Context, request entity.UpdateCustomerRequest)
+	CreateBatch(ctx context.Context, request entity.CreateCustomerBatchRequest) (response entity.BatchResult)
+	CreateBatchPartial(ctx context.Context, request entity.CreateCustomerBatchRequest) (response []entity.BatchItemResult)
+	Update(ctx context.Context, request entity.UpdateCustomerRequest) (version int)
+	Patch(ctx context.Context, request entity.PatchCustomerRequest) (version int)
+	BulkUpdate(ctx context.Context, request entity.BulkUpdateCustomerRequest) (response entity.BulkUpdateResult)
 	DeleteBatch(ctx context.Context, request entity.DeleteBatchCustomerRequest)
+	DeleteBatchPartial(ctx context.Context, request entity.DeleteBatchCustomerRequest) (response []entity.BatchItemResult)
+	RestoreBatch(ctx context.Context, request entity.RestoreBatchCustomerRequest)
+	PurgeBatch(ctx context.Context, request entity.PurgeBatchCustomerRequest)
 	FindById(ctx context.Context, request entity.CustomerParams) (response entity.CustomerResponse)
+	Lookup(ctx context.Context, request entity.LookupCustomerRequest) (response entity.LookupCustomerResponse)
 	FindAll(ctx context.Context, dataFilter entity.CustomerQueryFilter) (response []entity.CustomerResponse)
 	FindAllPaging(ctx context.Context, dataFilter entity.CustomerQueryFilter) (response []entity.CustomerResponse, paging entity.Meta)
-	Export(ctx context.Context, dataFilter entity.CustomerQueryFilter) (string, error)
-	Import(ctx context.Context, request entity.UploadCustomerRequest) error
+	Export(ctx context.Context, dataFilter entity.CustomerQueryFilter, format string, options tabular.Options, writer io.Writer) error
+	Import(ctx context.Context, request entity.UploadCustomerRequest, report io.Writer) (entity.BatchResult, error)
+	ImportPreview(ctx context.Context, request entity.UploadCustomerRequest) (entity.ImportPreviewResponse, error)
+	ImportTemplate(ctx context.Context, writer io.Writer) error
 }
 
 type CustomerUsecaseImpl struct {
@@ -51,16 +65,39 @@ func (usecase *CustomerUsecaseImpl) Create(ctx context.Context, request entity.C
 		Address:  request.Address,
 	}
 
-	err = usecase.customerRepo.Insert(ctx, dataset)
-	if err != nil {
+	_, err = usecase.customerRepo.Insert(ctx, dataset)
+	switch {
+	case errors.Is(err, repo.ErrEmailTaken):
+		panic(exception.NewBadRequestHandler(err.Error()))
+	case err != nil:
 		panic(exception.NewInternalServerErrorHandler(err.Error()))
 	}
 }
 
-func (usecase *CustomerUsecaseImpl) CreateBatch(ctx context.Context, request entity.CreateCustomerBatchRequest) {
-	err := usecase.validate.Struct(request)
+// CreateBatch inserts the customers in one transaction, request.OnConflict decides whether a taken
+// email fails the batch (the default), is skipped or overwrites the existing customer.
+func (usecase *CustomerUsecaseImpl) CreateBatch(ctx context.Context, request entity.CreateCustomerBatchRequest) (response entity.BatchResult) {
+	onConflict := onConflictMode(request.OnConflict)
+
+	// duplicates are resolved by the database when upserting, so the unique rule only applies in error mode
+	validateCtx := ctx
+	if onConflict != entity.OnConflictError {
+		validateCtx = utils.WithoutUniqueCheck(ctx)
+	}
+	err := usecase.validate.StructCtx(validateCtx, request)
 	helper.ErrorPanic(err)
 
+	// Postgres refuses to update the same row twice in one statement
+	if onConflict == entity.OnConflictUpdate {
+		seen := map[string]bool{}
+		for i, req := range request.Customers {
+			if seen[req.Email] {
+				panic(exception.NewBadRequestHandler(fmt.Sprintf("customers[%d]: email '%s' appears more than once", i, req.Email)))
+			}
+			seen[req.Email] = true
+		}
+	}
+
 	var customers []model.Customer
 	for _, req := range request.Customers {
 		customer := model.Customer{
@@ -74,13 +111,57 @@ func (usecase *CustomerUsecaseImpl) CreateBatch(ctx context.Context, request ent
 
 	batchSize := len(request.Customers)
 
-	err = usecase.customerRepo.InsertBatch(ctx, customers, batchSize)
-	if err != nil {
-		panic(exception.NewInternalServerErrorHandler(err.Error()))
+	response, err = usecase.customerRepo.InsertBatch(ctx, customers, batchSize, onConflict)
+	helper.ErrorPanic(insertBatchError(err))
+
+	return response
+}
+
+// CreateBatchPartial creates every customer on its own, so one invalid or duplicate customer only
+// fails its own item instead of the whole batch.
+func (usecase *CustomerUsecaseImpl) CreateBatchPartial(ctx context.Context, request entity.CreateCustomerBatchRequest) (response []entity.BatchItemResult) {
+	if len(request.Customers) == 0 {
+		panic(exception.NewBadRequestHandler("customers is required"))
+	}
+	if onConflictMode(request.OnConflict) != entity.OnConflictError {
+		panic(exception.NewBadRequestHandler("on_conflict cannot be combined with partial mode"))
+	}
+
+	for i, req := range request.Customers {
+		item := entity.BatchItemResult{Index: i}
+
+		err := usecase.validate.StructCtx(ctx, req)
+		if validationErrors, ok := err.(validator.ValidationErrors); ok {
+			item.Outcome = entity.BatchOutcomeInvalid
+			item.Errors = exception.ValidationReport(validationErrors)
+			response = append(response, item)
+			continue
+		}
+
+		item.ID, err = usecase.customerRepo.Insert(ctx, model.Customer{
+			Username: req.Username,
+			Email:    req.Email,
+			Phone:    req.Phone,
+			Address:  req.Address,
+		})
+		switch {
+		case errors.Is(err, repo.ErrEmailTaken):
+			// raced with another insert after validation, report it the way the validator would
+			item.Outcome = entity.BatchOutcomeInvalid
+			item.Errors = map[string]string{"email": err.Error()}
+		case err != nil:
+			item.Outcome = entity.BatchOutcomeFailed
+			item.Message = err.Error()
+		default:
+			item.Outcome = entity.BatchOutcomeCreated
+		}
+		response = append(response, item)
 	}
+
+	return response
 }
 
-func (usecase *CustomerUsecaseImpl) Update(ctx context.Context, request entity.UpdateCustomerRequest) {
+func (usecase *CustomerUsecaseImpl) Update(ctx context.Context, request entity.UpdateCustomerRequest) (version int) {
 	err := usecase.validate.Struct(request)
 	helper.ErrorPanic(err)
 
@@ -89,15 +170,212 @@ func (usecase *CustomerUsecaseImpl) Update(ctx context.Context, request entity.U
 		panic(exception.NewNotFoundHandler(err.Error()))
 	}
 
+	if request.Version != utils.AnyETag && dataset.Version != request.Version {
+		panic(exception.NewPreconditionFailedHandler(repo.ErrVersionConflict.Error()))
+	}
+
 	dataset.Username = request.Username
 	dataset.Email = request.Email
 	dataset.Phone = request.Phone
 	dataset.Address = request.Address
 
 	err = usecase.customerRepo.Update(ctx, dataset)
+	switch {
+	case errors.Is(err, repo.ErrVersionConflict):
+		panic(exception.NewPreconditionFailedHandler(err.Error()))
+	case errors.Is(err, repo.ErrEmailTaken):
+		panic(exception.NewBadRequestHandler(err.Error()))
+	case err != nil:
+		panic(exception.NewInternalServerErrorHandler(err.Error()))
+	}
+
+	return dataset.Version + 1
+}
+
+// Patch applies a merge patch or JSON Patch to the stored customer and only validates the fields
+// the patch actually changed, so a patch never trips over rules for fields it did not touch.
+func (usecase *CustomerUsecaseImpl) Patch(ctx context.Context, request entity.PatchCustomerRequest) (version int) {
+	dataset, err := usecase.customerRepo.FindById(ctx, request.ID)
 	if err != nil {
 		panic(exception.NewNotFoundHandler(err.Error()))
 	}
+
+	if request.Version != utils.AnyETag && dataset.Version != request.Version {
+		panic(exception.NewPreconditionFailedHandler(repo.ErrVersionConflict.Error()))
+	}
+
+	current := entity.UpdateCustomerRequest{
+		ID:       dataset.ID,
+		Username: dataset.Username,
+		Email:    dataset.Email,
+		Phone:    dataset.Phone,
+		Address:  dataset.Address,
+	}
+	original := []byte(helper.StructToJson(current))
+
+	var patched []byte
+	switch request.ContentType {
+	case "application/json-patch+json":
+		patch, err := jsonpatch.DecodePatch(request.Patch)
+		if err != nil {
+			panic(exception.NewBadRequestHandler(err.Error()))
+		}
+		patched, err = patch.Apply(original)
+		if err != nil {
+			panic(exception.NewBadRequestHandler(err.Error()))
+		}
+	default:
+		patched, err = jsonpatch.MergePatch(original, request.Patch)
+		if err != nil {
+			panic(exception.NewBadRequestHandler(err.Error()))
+		}
+	}
+
+	changed := changedCustomerFields(original, patched)
+	if len(changed) == 0 {
+		return dataset.Version
+	}
+
+	var updated entity.UpdateCustomerRequest
+	if err := json.Unmarshal(patched, &updated); err != nil {
+		panic(exception.NewBadRequestHandler(err.Error()))
+	}
+	updated.ID = dataset.ID
+	updated.Version = dataset.Version
+
+	err = usecase.validate.StructPartial(updated, changed...)
+	helper.ErrorPanic(err)
+
+	dataset.Username = updated.Username
+	dataset.Email = updated.Email
+	dataset.Phone = updated.Phone
+	dataset.Address = updated.Address
+
+	err = usecase.customerRepo.Update(ctx, dataset)
+	switch {
+	case errors.Is(err, repo.ErrVersionConflict):
+		panic(exception.NewPreconditionFailedHandler(err.Error()))
+	case errors.Is(err, repo.ErrEmailTaken):
+		panic(exception.NewBadRequestHandler(err.Error()))
+	case err != nil:
+		panic(exception.NewInternalServerErrorHandler(err.Error()))
+	}
+
+	return dataset.Version + 1
+}
+
+// BulkUpdate changes many customers in one transaction, by item or by filter, see
+// entity.BulkUpdateCustomerRequest. With DryRun it only reports how many customers would change.
+func (usecase *CustomerUsecaseImpl) BulkUpdate(ctx context.Context, request entity.BulkUpdateCustomerRequest) (response entity.BulkUpdateResult) {
+	switch {
+	case len(request.Items) > 0 && request.Filter == nil && len(request.Set) == 0:
+		response = usecase.bulkUpdateItems(ctx, request.Items, request.DryRun)
+	case len(request.Items) == 0 && request.Filter != nil && len(request.Set) > 0:
+		response = usecase.bulkUpdateFilter(ctx, *request.Filter, request.Set, request.DryRun)
+	default:
+		panic(exception.NewBadRequestHandler("send either items, or filter together with set"))
+	}
+
+	response.DryRun = request.DryRun
+	return response
+}
+
+func (usecase *CustomerUsecaseImpl) bulkUpdateItems(ctx context.Context, items []entity.BulkUpdateCustomerItem, dryRun bool) (response entity.BulkUpdateResult) {
+	fieldNames := helper.JsonFieldNames(entity.UpdateCustomerRequest{})
+	validation := entity.BulkUpdateCustomerValidation{}
+	var customers []model.Customer
+	var partial []string
+	seen := map[int]bool{}
+
+	for i, item := range items {
+		if seen[item.ID] {
+			panic(exception.NewBadRequestHandler(fmt.Sprintf("items[%d]: customer %d appears more than once", i, item.ID)))
+		}
+		seen[item.ID] = true
+
+		dataset, err := usecase.customerRepo.FindById(ctx, item.ID)
+		if err != nil {
+			panic(exception.NewNotFoundHandler(fmt.Sprintf("items[%d]: %s", i, err.Error())))
+		}
+
+		customer := entity.UpdateCustomerRequest{
+			ID:       dataset.ID,
+			Version:  dataset.Version,
+			Username: dataset.Username,
+			Email:    dataset.Email,
+			Phone:    dataset.Phone,
+			Address:  dataset.Address,
+		}
+		changed := assignCustomerFields(&customer, item.Fields, fmt.Sprintf("items[%d]", i))
+		for _, field := range changed {
+			partial = append(partial, fmt.Sprintf("Items[%d].%s", i, fieldNames[field]))
+		}
+
+		validation.Items = append(validation.Items, customer)
+		if len(changed) > 0 {
+			response.Changed++
+			dataset.Username = customer.Username
+			dataset.Email = customer.Email
+			dataset.Phone = customer.Phone
+			dataset.Address = customer.Address
+			customers = append(customers, dataset)
+		}
+	}
+	response.Matched = len(items)
+
+	if len(partial) > 0 {
+		err := usecase.validate.StructPartial(validation, partial...)
+		helper.ErrorPanic(err)
+	}
+
+	if dryRun || len(customers) == 0 {
+		return response
+	}
+
+	err := usecase.customerRepo.UpdateBatch(ctx, customers)
+	switch {
+	case errors.Is(err, repo.ErrVersionConflict):
+		panic(exception.NewPreconditionFailedHandler(err.Error()))
+	case errors.Is(err, repo.ErrEmailTaken):
+		panic(exception.NewBadRequestHandler(err.Error()))
+	case err != nil:
+		panic(exception.NewInternalServerErrorHandler(err.Error()))
+	}
+
+	return response
+}
+
+func (usecase *CustomerUsecaseImpl) bulkUpdateFilter(ctx context.Context, filter entity.CustomerQueryFilter, set map[string]string, dryRun bool) (response entity.BulkUpdateResult) {
+	if _, ok := set["email"]; ok {
+		panic(exception.NewBadRequestHandler("email must stay unique and cannot be set by filter"))
+	}
+
+	// an empty filter would silently rewrite every customer
+	if filter.Query == "" && len(filter.Filters) == 0 && filter.IsActive == nil && filter.Username == "" &&
+		filter.Email == "" && (filter.StartDate == "" || filter.EndDate == "") {
+		panic(exception.NewBadRequestHandler("filter must have at least one condition"))
+	}
+	filter.IncludeDeleted = false
+
+	customer := entity.UpdateCustomerRequest{}
+	changed := assignCustomerFields(&customer, set, "set")
+	fieldNames := helper.JsonFieldNames(entity.UpdateCustomerRequest{})
+	var partial []string
+	for _, field := range changed {
+		partial = append(partial, fieldNames[field])
+	}
+	err := usecase.validate.StructPartial(customer, partial...)
+	helper.ErrorPanic(err)
+
+	response.Matched, response.Changed, err = usecase.customerRepo.UpdateByFilter(ctx, filter, set, dryRun)
+	switch {
+	case errors.Is(err, repo.ErrEmailTaken):
+		panic(exception.NewBadRequestHandler(err.Error()))
+	case err != nil:
+		panic(exception.NewInternalServerErrorHandler(err.Error()))
+	}
+
+	return response
 }
 
 func (usecase *CustomerUsecaseImpl) DeleteBatch(ctx context.Context, request entity.DeleteBatchCustomerRequest) {
@@ -110,6 +388,67 @@ func (usecase *CustomerUsecaseImpl) DeleteBatch(ctx context.Context, request ent
 	}
 }
 
+// DeleteBatchPartial deletes every id on its own and reports the ones that do not exist instead of
+// failing the whole batch.
+func (usecase *CustomerUsecaseImpl) DeleteBatchPartial(ctx context.Context, request entity.DeleteBatchCustomerRequest) (response []entity.BatchItemResult) {
+	if len(request.ID) == 0 {
+		panic(exception.NewBadRequestHandler("id is required"))
+	}
+
+	for i, id := range request.ID {
+		item := entity.BatchItemResult{Index: i, ID: id}
+
+		if id <= 0 {
+			item.Outcome = entity.BatchOutcomeInvalid
+			item.Errors = map[string]string{"id": "id is required"}
+			response = append(response, item)
+			continue
+		}
+
+		err := usecase.customerRepo.DeleteBatch(ctx, []int{id})
+		switch {
+		case errors.Is(err, repo.ErrCustomerNotFound):
+			item.Outcome = entity.BatchOutcomeNotFound
+		case err != nil:
+			item.Outcome = entity.BatchOutcomeFailed
+			item.Message = err.Error()
+		default:
+			item.Outcome = entity.BatchOutcomeDeleted
+		}
+		response = append(response, item)
+	}
+
+	return response
+}
+
+func (usecase *CustomerUsecaseImpl) RestoreBatch(ctx context.Context, request entity.RestoreBatchCustomerRequest) {
+	err := usecase.validate.Struct(request)
+	helper.ErrorPanic(err)
+
+	err = usecase.customerRepo.RestoreBatch(ctx, request.ID)
+	switch {
+	case errors.Is(err, repo.ErrEmailTaken):
+		panic(exception.NewBadRequestHandler("cannot restore: the email of a customer now belongs to another customer"))
+	case errors.Is(err, repo.ErrCustomerNotFound):
+		panic(exception.NewNotFoundHandler(err.Error()))
+	case err != nil:
+		panic(exception.NewInternalServerErrorHandler(err.Error()))
+	}
+}
+
+func (usecase *CustomerUsecaseImpl) PurgeBatch(ctx context.Context, request entity.PurgeBatchCustomerRequest) {
+	err := usecase.validate.Struct(request)
+	helper.ErrorPanic(err)
+
+	err = usecase.customerRepo.PurgeBatch(ctx, request.ID)
+	switch {
+	case errors.Is(err, repo.ErrCustomerNotFound):
+		panic(exception.NewNotFoundHandler(err.Error()))
+	case err != nil:
+		panic(exception.NewInternalServerErrorHandler(err.Error()))
+	}
+}
+
 func (usecase *CustomerUsecaseImpl) FindById(ctx context.Context, request entity.CustomerParams) (response entity.CustomerResponse) {
 	result, err := usecase.customerRepo.FindById(ctx, request.CustomerId)
 
@@ -121,6 +460,44 @@ func (usecase *CustomerUsecaseImpl) FindById(ctx context.Context, request entity
 	return response
 }
 
+// Lookup resolves many ids in one query, keeping the order of the request and dropping repeats.
+func (usecase *CustomerUsecaseImpl) Lookup(ctx context.Context, request entity.LookupCustomerRequest) (response entity.LookupCustomerResponse) {
+	err := usecase.validate.Struct(request)
+	helper.ErrorPanic(err)
+
+	result, err := usecase.customerRepo.FindByIds(ctx, request.ID)
+	if err != nil {
+		panic(exception.NewInternalServerErrorHandler(err.Error()))
+	}
+
+	found := make(map[int]model.Customer, len(result))
+	for _, customer := range result {
+		found[customer.ID] = customer
+	}
+
+	response.Customers = []entity.CustomerResponse{}
+	response.Missing = []int{}
+	seen := map[int]bool{}
+	for _, id := range request.ID {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		customer, ok := found[id]
+		if !ok {
+			response.Missing = append(response.Missing, id)
+			continue
+		}
+
+		var data entity.CustomerResponse
+		helper.Automapper(customer, &data)
+		response.Customers = append(response.Customers, data)
+	}
+
+	return response
+}
+
 func (usecase *CustomerUsecaseImpl) FindAll(ctx context.Context, dataFilter entity.CustomerQueryFilter) (response []entity.CustomerResponse) {
 	result, err := usecase.customerRepo.FindAll(ctx, dataFilter)
 
@@ -137,8 +514,16 @@ func (usecase *CustomerUsecaseImpl) FindAll(ctx context.Context, dataFilter enti
 }
 
 func (usecase *CustomerUsecaseImpl) FindAllPaging(ctx context.Context, dataFilter entity.CustomerQueryFilter) (response []entity.CustomerResponse, paging entity.Meta) {
+	// defaults have to be in place before the query, otherwise the repo skips LIMIT/OFFSET entirely
+	if dataFilter.Limit <= 0 {
+		dataFilter.Limit = 10
+	}
 
-	result := usecase.customerRepo.FindAllPaging(ctx, dataFilter)
+	if dataFilter.Page <= 0 {
+		dataFilter.Page = 1
+	}
+
+	result, paging := usecase.customerRepo.FindAllPaging(ctx, dataFilter)
 
 	for _, value := range result {
 		var res entity.CustomerResponse
@@ -147,234 +532,517 @@ func (usecase *CustomerUsecaseImpl) FindAllPaging(ctx context.Context, dataFilte
 		response = append(response, res)
 	}
 
-	if dataFilter.Limit == 0 {
-		dataFilter.Limit = 10
+	paging.Page = dataFilter.Page
+	paging.Limit = dataFilter.Limit
+	paging.TotalPage = int(math.Ceil(float64(paging.TotalData) / float64(dataFilter.Limit)))
+
+	return response, paging
+}
+
+// Export streams the customers matching dataFilter from a database cursor into writer in the
+// given format. An xlsx workbook is only written once all rows were read, so a failing query can
+// still be answered with an error response; csv and jsonl rows go out as their buffer fills.
+func (usecase *CustomerUsecaseImpl) Export(ctx context.Context, dataFilter entity.CustomerQueryFilter, format string, options tabular.Options, writer io.Writer) error {
+	options.Sheet = customerImportSheet
+	table, err := tabular.NewWriter(format, writer, customerColumns, options)
+	if err != nil {
+		return err
 	}
 
-	if dataFilter.Page == 0 {
-		dataFilter.Page = 1
+	if err := writeCustomers(ctx, usecase.customerRepo, dataFilter, table, nil); err != nil {
+		table.Abort()
+		if badRequest, ok := err.(*exception.BadRequestStruct); ok {
+			return badRequest
+		}
+		return exception.NewInternalServerErrorHandler(err.Error())
 	}
 
-	var total int
-	if len(result) > 0 {
-		total = len(result)
-	} else {
-		total = 0
+	return table.Close()
+}
+
+// Import inserts the customers of the uploaded file when every row is valid. Otherwise it returns
+// the *exception.ExcelValidation and, with a non-nil report, also writes the annotated file there
+// from the same pass, see importErrorReport.
+func (usecase *CustomerUsecaseImpl) Import(ctx context.Context, request entity.UploadCustomerRequest, report io.Writer) (entity.BatchResult, error) {
+	file, err := usecase.readImport(ctx, request)
+	if _, invalid := err.(*exception.ExcelValidation); err != nil && !invalid {
+		return entity.BatchResult{}, err
 	}
 
-	paging.Page = dataFilter.Page
-	paging.Limit = dataFilter.Limit
-	paging.TotalData = total
-	paging.TotalPage = int(math.Ceil(float64(total) / float64(dataFilter.Limit)))
+	// If there are any validation errors, return them
+	if len(file.Validation.Errors) > 0 {
+		if report != nil {
+			if err := importErrorReport(request, file, report); err != nil {
+				return entity.BatchResult{}, err
+			}
+		}
+		return entity.BatchResult{}, &file.Validation
+	}
 
-	return response, paging
+	customers := make([]model.Customer, 0, len(file.Rows))
+	for _, row := range file.Rows {
+		customers = append(customers, row.Customer)
+	}
+
+	// Insert batch of customers into the database
+	result, err := usecase.customerRepo.InsertBatch(ctx, customers, len(customers), file.OnConflict)
+	return result, insertBatchError(err)
 }
 
-func (usecase *CustomerUsecaseImpl) Export(ctx context.Context, dataFilter entity.CustomerQueryFilter) (string, error) {
-	excel := excelize.NewFile()
-	defer func() {
-		if err := excel.Close(); err != nil {
-			panic(exception.NewInternalServerErrorHandler(err.Error()))
-		}
-	}()
+// insertBatchError turns an InsertBatch error into the exception answering it: a taken email is
+// the client's to fix, anything else is ours.
+func insertBatchError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, repo.ErrEmailTaken):
+		return exception.NewBadRequestHandler(err.Error())
+	default:
+		return exception.NewInternalServerErrorHandler(err.Error())
+	}
+}
 
-	//sheet
-	mstCustomer := "MST_CUSTOMER"
-	index, err := excel.NewSheet(mstCustomer)
-	if err != nil {
-		return "", exception.NewInternalServerErrorHandler(err.Error())
+// ImportPreview runs every check of Import without writing anything and tells what the import
+// would do, with the first request.PreviewRows rows as they were parsed.
+func (usecase *CustomerUsecaseImpl) ImportPreview(ctx context.Context, request entity.UploadCustomerRequest) (entity.ImportPreviewResponse, error) {
+	if err := usecase.validate.StructPartial(request, "PreviewRows"); err != nil {
+		return entity.ImportPreviewResponse{}, err
+	}
+	if request.PreviewRows == 0 {
+		request.PreviewRows = 10
 	}
 
-	err = excel.DeleteSheet("Sheet1")
+	file, err := usecase.readImport(ctx, request)
 	if err != nil {
-		return "", exception.NewInternalServerErrorHandler(err.Error())
+		return entity.ImportPreviewResponse{}, err
 	}
+	onConflict, rows := file.OnConflict, file.Rows
 
-	result, err := usecase.customerRepo.FindAll(ctx, dataFilter)
-	if err != nil {
-		return "", exception.NewInternalServerErrorHandler(err.Error())
+	response := entity.ImportPreviewResponse{
+		Errors: file.Validation.Errors,
+		Rows:   []entity.ImportPreviewRow{},
 	}
+	for _, row := range rows {
+		action := entity.ImportActionInsert
+		switch {
+		case len(row.Errors) > 0:
+			action = entity.ImportActionInvalid
+			response.RowsInvalid++
+		case row.Exists && onConflict == entity.OnConflictUpdate:
+			action = entity.ImportActionUpdate
+			response.WouldUpdate++
+		case row.Exists:
+			action = entity.ImportActionSkip
+			response.WouldSkip++
+		default:
+			response.WouldInsert++
+		}
+		if action != entity.ImportActionInvalid {
+			response.RowsValid++
+		}
 
-	// Define headers
-	headers := []string{"ID", "Name", "Email", "Phone", "Address"}
+		if len(response.Rows) < request.PreviewRows {
+			response.Rows = append(response.Rows, entity.ImportPreviewRow{
+				Row:      row.Row,
+				Action:   action,
+				Username: row.Customer.Username,
+				Email:    row.Customer.Email,
+				Phone:    row.Customer.Phone,
+				Address:  row.Customer.Address,
+				Errors:   row.Errors,
+			})
+		}
+	}
 
-	// Set headers and apply styles
-	headerStyle, err := excel.NewStyle(&excelize.Style{
-		Font: &excelize.Font{Bold: true},
-		Fill: excelize.Fill{
-			Type:    "pattern",
-			Pattern: 1,
-			Color:   []string{"#FFFF00"},
-		},
-	})
+	return response, nil
+}
 
+// ImportTemplate writes an empty xlsx import file, its validations and instructions come from
+// customerImportSchema.
+func (usecase *CustomerUsecaseImpl) ImportTemplate(ctx context.Context, writer io.Writer) error {
+	file, err := customerImportSchema.Template(customerImportSheet)
 	if err != nil {
-		return "", exception.NewInternalServerErrorHandler(err.Error())
+		return exception.NewInternalServerErrorHandler(err.Error())
 	}
+	defer file.Close()
 
-	for i, header := range headers {
-		cell := fmt.Sprintf("%s1", string(rune('A'+i)))
-		excel.SetCellValue(mstCustomer, cell, header)
-		excel.SetCellStyle(mstCustomer, cell, cell, headerStyle)
+	if _, err := file.WriteTo(writer); err != nil {
+		return exception.NewInternalServerErrorHandler(err.Error())
 	}
+	return nil
+}
 
-	// customer the sheet with data
-	for i, customer := range result {
-		excel.SetCellValue(mstCustomer, fmt.Sprintf("A%d", i+2), customer.ID)
-		excel.SetCellValue(mstCustomer, fmt.Sprintf("B%d", i+2), customer.Username)
-		excel.SetCellValue(mstCustomer, fmt.Sprintf("C%d", i+2), customer.Email)
-		excel.SetCellValue(mstCustomer, fmt.Sprintf("D%d", i+2), customer.Phone)
-		excel.SetCellValue(mstCustomer, fmt.Sprintf("E%d", i+2), customer.Address)
+// importErrorReport writes the already read import file back as xlsx with its errors marked, see
+// helper.AnnotateImport. Excel files are annotated as they were sent so they keep their look, the
+// other formats are laid out on a new MST_CUSTOMER sheet.
+func importErrorReport(request entity.UploadCustomerRequest, file importFile, writer io.Writer) error {
+	var importErrors []helper.ImportError
+	positions := make([]int, 0, len(file.HeaderErrors))
+	for position := range file.HeaderErrors {
+		positions = append(positions, position)
+	}
+	sort.Ints(positions)
+	for _, position := range positions {
+		for _, message := range file.HeaderErrors[position] {
+			importErrors = append(importErrors, helper.ImportError{Row: 1, Column: position, Field: "header", Message: message})
+		}
+	}
+	for _, row := range file.Rows {
+		for _, column := range customerImportSchema.Columns {
+			position, ok := file.Columns[column.Field]
+			if !ok {
+				position = -1
+			}
+			for _, message := range row.Errors[column.Field] {
+				importErrors = append(importErrors, helper.ImportError{Row: row.Row, Column: position, Field: column.Field, Message: message})
+			}
+		}
 	}
 
-	excel.SetActiveSheet(index)
+	excel, sheet, err := importWorkbook(request, file)
+	if err != nil {
+		return exception.NewInternalServerErrorHandler(err.Error())
+	}
+	defer excel.Close()
 
-	timestamp := time.Now().Format("2006-01-02_150405")
-	filePath := fmt.Sprintf("customer_%s.xlsx", timestamp)
+	if err := helper.AnnotateImport(excel, sheet, importErrors); err != nil {
+		return exception.NewInternalServerErrorHandler(err.Error())
+	}
+	if _, err := excel.WriteTo(writer); err != nil {
+		return exception.NewInternalServerErrorHandler(err.Error())
+	}
+	return nil
+}
+
+// importWorkbook opens the uploaded workbook, or copies the rows of another format into a new one,
+// and tells the sheet holding the customers.
+func importWorkbook(request entity.UploadCustomerRequest, file importFile) (*excelize.File, string, error) {
+	if file.Format == tabular.FormatXlsx {
+		src, err := request.File.Open()
+		if err != nil {
+			return nil, "", err
+		}
+		defer src.Close()
+
+		excel, err := excelize.OpenReader(src)
+		if err != nil {
+			return nil, "", err
+		}
+		return excel, tabular.XlsxSheet(excel, customerImportSheet), nil
+	}
 
-	if err := excel.SaveAs(filePath); err != nil {
-		return "", exception.NewInternalServerErrorHandler(err.Error())
+	excel := excelize.NewFile()
+	if err := excel.SetSheetName("Sheet1", customerImportSheet); err != nil {
+		return nil, "", err
 	}
+	if err := excel.SetSheetRow(customerImportSheet, "A1", &file.Header); err != nil {
+		return nil, "", err
+	}
+	for _, row := range file.Rows {
+		if err := excel.SetSheetRow(customerImportSheet, fmt.Sprintf("A%d", row.Row), &row.Cells); err != nil {
+			return nil, "", err
+		}
+	}
+	return excel, customerImportSheet, nil
+}
+
+// customerImportSchema decodes import rows into create requests, so a file goes through the same
+// validation as POST /customers.
+var customerImportSchema = helper.ImportSchema{
+	Target: entity.CreateCustomerRequest{},
+	Columns: []helper.ImportColumn{
+		{Field: "username", Aliases: []string{"username", "user name", "name"}, Description: "Name of the customer"},
+		{Field: "email", Aliases: []string{"email", "e-mail", "email address"}, Description: "Email address, identifies the customer when on_conflict is skip or update"},
+		{Field: "phone", Aliases: []string{"phone", "phone number", "telephone", "mobile"}, Description: "Phone number, the cells are text so leading zeros stay", Text: true},
+		{Field: "address", Aliases: []string{"address"}, Description: "Postal address"},
+	},
+	// exports start with the id, it is accepted so they can be imported again
+	Ignored: []string{"id"},
+	Examples: [][]string{
+		{"John Doe", "john.doe@example.com", "081234567890", "Jl. Sudirman No. 1, Jakarta"},
+		{"Jane Roe", "jane.roe@example.com", "089876543210", "Jl. Asia Afrika No. 8, Bandung"},
+	},
+}
 
-	return filePath, nil
+// customerImportSheet is the sheet xlsx imports read customers from.
+const customerImportSheet = "MST_CUSTOMER"
+
+// importRow is one parsed row of an import file, Row being its line number in the file.
+type importRow struct {
+	Row      int
+	Cells    []string
+	Customer model.Customer
+	// Exists tells the email already belongs to a customer
+	Exists bool
+	Errors map[string][]string
 }
 
-func (usecase *CustomerUsecaseImpl) Import(ctx context.Context, request entity.UploadCustomerRequest) error {
-	// Open the Excel file from the request
+// importFile is an import file once read and validated.
+type importFile struct {
+	OnConflict string
+	Format     string
+	Header     []string
+	// Columns is the position of every field found in Header
+	Columns map[string]int
+	// HeaderErrors are the messages of the unknown and repeated headers by position, the missing
+	// columns being under -1
+	HeaderErrors map[int][]string
+	Rows         []importRow
+	Validation   exception.ExcelValidation
+}
+
+// readImport parses and validates the whole import file. Every row is returned, the invalid ones
+// carry their errors, which are also collected in Validation. A header that is not usable stops
+// the reading: the file is returned without rows along with its Validation as the error.
+func (usecase *CustomerUsecaseImpl) readImport(ctx context.Context, request entity.UploadCustomerRequest) (file importFile, err error) {
+	if err := usecase.validate.StructPartial(request, "OnConflict", "ErrorReport"); err != nil {
+		return file, err
+	}
+	file.OnConflict = onConflictMode(request.OnConflict)
+
+	options, err := tabular.ParseOptions(request.Delimiter, request.Encoding)
+	if err != nil {
+		return file, err
+	}
+	// Excel files keep the customers on this sheet, other sheets are only read when it is missing
+	options.Sheet = customerImportSheet
+
+	// Open the file from the request
 	src, err := request.File.Open()
 	if err != nil {
-		return exception.NewInternalServerErrorHandler(err.Error())
+		return file, exception.NewInternalServerErrorHandler(err.Error())
 	}
 	defer src.Close()
 
-	// Initialize Excel reader
-	xlFile, err := excelize.OpenReader(src)
+	// The format comes from the content, not from the file name
+	format, content, err := tabular.Detect(src)
 	if err != nil {
-		return exception.NewInternalServerErrorHandler(err.Error())
+		return file, err
 	}
+	file.Format = format
 
-	// Define the sheet name to read from
-	sheetName := "MST_CUSTOMER"
+	rows, err := tabular.NewReader(format, content, options)
+	if err != nil {
+		return file, exception.NewBadRequestHandler(err.Error())
+	}
+	defer rows.Close()
 
-	// Read all rows from the sheet
-	rows, err := xlFile.GetRows(sheetName)
+	// Columns are found by their header, so the file may order them as it likes
+	file.Header, err = rows.Read()
+	if err == io.EOF {
+		return file, exception.NewBadRequestHandler("file is empty")
+	}
 	if err != nil {
-		return exception.NewInternalServerErrorHandler(err.Error())
+		return file, exception.NewBadRequestHandler(fmt.Sprintf("row 1: %s", err.Error()))
 	}
 
-	// Use error group to manage concurrent operations
-	g, _ := errgroup.WithContext(ctx)
-	chanUser := make(chan model.Customer)
-	excelValidation := exception.ExcelValidation{}
+	columns, missing, unknown, duplicate := customerImportSchema.Header(file.Header)
+	file.Columns = columns
+	file.HeaderErrors = map[int][]string{}
+	for _, field := range missing {
+		message := fmt.Sprintf("column %s is missing", field)
+		file.HeaderErrors[-1] = append(file.HeaderErrors[-1], message)
+		file.Validation.AddHandler("header", 1, message)
+	}
+	for _, i := range unknown {
+		message := fmt.Sprintf("column '%s' is unknown", file.Header[i])
+		file.HeaderErrors[i] = append(file.HeaderErrors[i], message)
+		file.Validation.AddHandler("header", 1, message)
+	}
+	for _, i := range duplicate {
+		message := fmt.Sprintf("column '%s' is repeated", file.Header[i])
+		file.HeaderErrors[i] = append(file.HeaderErrors[i], message)
+		file.Validation.AddHandler("header", 1, message)
+	}
+	if len(file.Validation.Errors) > 0 {
+		return file, &file.Validation
+	}
+
+	// The unique rule is checked below for the whole file at once, so the validator skips it
+	validateCtx := utils.WithoutUniqueCheck(ctx)
 	uniqueTracker := make(map[string]map[string]bool)
-	rowErrors := map[string][]string{}
-
-	// Initialize uniqueTracker for each field based on validation rules
-	for _, rule := range helper.RulesExcelCustomer {
-		fieldName := strings.Split(rule, ",")[0]
-		uniqueTracker[fieldName] = make(map[string]bool)
-	}
-
-	// Validate each row and cell dynamically
-	for rowIndex, row := range rows {
-		if rowIndex == 0 {
-			continue // Skip header row
-		}
-
-		// Validate each cell in the row based on rules
-		for colIndex, rule := range helper.RulesExcelCustomer {
-			fields := strings.Split(rule, ",")
-			fieldName := fields[0]
-			cell := row[colIndex]
-			rules := fields[1:]
-
-			for _, r := range rules {
-				switch r {
-				case "required":
-					if cell == "" {
-						rowErrors[fieldName] = append(rowErrors[fieldName], fmt.Sprintf("%s row %d is required", fieldName, rowIndex+1))
-					}
-				case "unique":
-					if uniqueTracker[fieldName][cell] {
-						rowErrors[fieldName] = append(rowErrors[fieldName], fmt.Sprintf("%s '%s' is not unique row %d", fieldName, cell, rowIndex+1))
-					}
-					uniqueTracker[fieldName][cell] = true
-				}
-			}
+	var uniqueFields []string
+	for _, column := range customerImportSchema.Columns {
+		if customerImportSchema.HasRule(column.Field, "unique") {
+			uniqueFields = append(uniqueFields, column.Field)
+			uniqueTracker[column.Field] = make(map[string]bool)
 		}
+	}
 
-		// Check unique constraint in the database
-		for colIndex, rule := range helper.RulesExcelCustomer {
-			fields := strings.Split(rule, ",")
-			fieldName := fields[0]
-			rules := fields[1:]
-
-			for _, r := range rules {
-				if r == "unique" {
-					cell := row[colIndex]
-					exists := usecase.customerRepo.CheckColumnExists(ctx, fieldName, cell)
-					if exists != false {
-						excelValidation.AddHandler(fieldName, rowIndex+1, fmt.Sprintf("%s '%s' already taken", fieldName, cell))
-					}
-				}
-			}
+	// Validate each row as a create request, reading one row at a time
+	for rowIndex := 1; ; rowIndex++ {
+		row, err := rows.Read()
+		if err == io.EOF {
+			break
 		}
+		if err != nil {
+			return file, exception.NewBadRequestHandler(fmt.Sprintf("row %d: %s", rowIndex+1, err.Error()))
+		}
+
+		request := entity.CreateCustomerRequest{}
+		customerImportSchema.Decode(row, columns, &request)
 
-		// If there are validation errors for this row, skip further processing
-		if len(rowErrors) > 0 {
-			for field, errs := range rowErrors {
-				for _, err := range errs {
-					excelValidation.AddHandler(field, rowIndex+1, err)
-				}
+		rowErrors := map[string][]string{}
+
+		err = usecase.validate.StructCtx(validateCtx, request)
+		if validationErrors, ok := err.(validator.ValidationErrors); ok {
+			for field, message := range exception.ValidationReport(validationErrors) {
+				rowErrors[field] = append(rowErrors[field], message)
 			}
-			continue
+		} else if err != nil {
+			return file, err
 		}
 
-		// Process valid rows concurrently
-		g.Go(func() error {
-			if len(row) < 4 {
-				return fmt.Errorf("invalid row length: %v", row)
+		for _, fieldName := range uniqueFields {
+			cell := customerImportSchema.Cell(row, columns, fieldName)
+			if cell == "" {
+				continue
 			}
-			customer := model.Customer{
-				Username: row[0],
-				Email:    row[1],
-				Phone:    row[2],
-				Address:  row[3],
+			if uniqueTracker[fieldName][cell] {
+				rowErrors[fieldName] = append(rowErrors[fieldName], fmt.Sprintf("%s '%s' is not unique", fieldName, cell))
 			}
-			chanUser <- customer
-			return nil
-		})
+			uniqueTracker[fieldName][cell] = true
+		}
+
+		customer := model.Customer{
+			Username: request.Username,
+			Email:    request.Email,
+			Phone:    request.Phone,
+			Address:  request.Address,
+		}
+
+		file.Rows = append(file.Rows, importRow{Row: rowIndex + 1, Cells: row, Customer: customer, Errors: rowErrors})
 	}
 
-	// Close the channel once all goroutines are done
-	go func() {
-		err := g.Wait()
+	// Check the unique constraint in the database with every value of the file at once, taken
+	// values are only an error when not upserting
+	for _, fieldName := range uniqueFields {
+		values := make([]string, 0, len(uniqueTracker[fieldName]))
+		for value := range uniqueTracker[fieldName] {
+			values = append(values, value)
+		}
+
+		existing, err := usecase.customerRepo.ExistingValues(ctx, fieldName, values)
 		if err != nil {
-			return
+			return file, exception.NewInternalServerErrorHandler(err.Error())
 		}
-		close(chanUser)
-	}()
 
-	// Collect customers from the channel
-	var customers []model.Customer
-	for customer := range chanUser {
-		customers = append(customers, customer)
+		for i := range file.Rows {
+			row := &file.Rows[i]
+			cell := customerImportSchema.Cell(row.Cells, columns, fieldName)
+			if !existing[cell] {
+				continue
+			}
+			row.Exists = true
+			if file.OnConflict == entity.OnConflictError {
+				row.Errors[fieldName] = append(row.Errors[fieldName], fmt.Sprintf("%s '%s' already taken", fieldName, cell))
+			}
+		}
 	}
 
-	// Wait for all goroutines to finish
-	if err := g.Wait(); err != nil {
-		return err
+	for _, row := range file.Rows {
+		for _, column := range customerImportSchema.Columns {
+			for _, err := range row.Errors[column.Field] {
+				file.Validation.AddHandler(column.Field, row.Row, err)
+			}
+		}
 	}
 
-	// If there are any validation errors, return them
-	if len(excelValidation.Errors) > 0 {
-		return &excelValidation
+	return file, nil
+}
+
+// customerColumns are the columns of a customer export, in every format.
+var customerColumns = []tabular.Column{
+	{Key: "id", Title: "ID"},
+	{Key: "username", Title: "Name"},
+	{Key: "email", Title: "Email"},
+	{Key: "phone", Title: "Phone"},
+	{Key: "address", Title: "Address"},
+}
+
+// writeCustomers is the customer export shared by the direct download and the export jobs, it
+// reads the customers off a database cursor into table without closing it. progress, when set,
+// is called with the number of rows written so far after every row.
+func writeCustomers(ctx context.Context, customerRepo repo.CustomerRepo, dataFilter entity.CustomerQueryFilter, table tabular.Writer, progress func(rows int) error) error {
+	written := 0
+	return customerRepo.StreamAll(ctx, dataFilter, func(customer entity.CustomerResponse) error {
+		written++
+		if err := table.Write([]interface{}{customer.ID, customer.Username, customer.Email, customer.Phone, customer.Address}); err != nil {
+			return err
+		}
+		if progress != nil {
+			return progress(written)
+		}
+		return nil
+	})
+}
+
+// onConflictMode resolves an on_conflict value, which validation already limited to the known modes.
+func onConflictMode(value string) string {
+	if value == "" {
+		return entity.OnConflictError
 	}
+	return value
+}
 
-	// Insert batch of customers into the database
-	if err := usecase.customerRepo.InsertBatch(ctx, customers, len(customers)); err != nil {
-		return err
+// assignCustomerFields writes fields, keyed by json name, into customer and returns the json names
+// of the ones that changed. at prefixes the error for a field that cannot be assigned.
+func assignCustomerFields(customer *entity.UpdateCustomerRequest, fields map[string]string, at string) []string {
+	targets := map[string]*string{
+		"username": &customer.Username,
+		"email":    &customer.Email,
+		"phone":    &customer.Phone,
+		"address":  &customer.Address,
 	}
 
-	return nil
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var changed []string
+	for _, name := range names {
+		target, ok := targets[name]
+		if !ok {
+			panic(exception.NewBadRequestHandler(fmt.Sprintf("%s: field '%s' cannot be updated", at, name)))
+		}
+		if *target != fields[name] {
+			*target = fields[name]
+			changed = append(changed, name)
+		}
+	}
+
+	return changed
+}
+
+// changedCustomerFields returns the Go names of the UpdateCustomerRequest fields that differ
+// between the two documents, rejecting fields a patch may not add or touch.
+func changedCustomerFields(original []byte, patched []byte) []string {
+	var before, after map[string]interface{}
+	if err := json.Unmarshal(original, &before); err != nil {
+		panic(exception.NewInternalServerErrorHandler(err.Error()))
+	}
+	if err := json.Unmarshal(patched, &after); err != nil {
+		panic(exception.NewBadRequestHandler("patch must produce a JSON object"))
+	}
+
+	fieldNames := helper.JsonFieldNames(entity.UpdateCustomerRequest{})
+	var changed []string
+	for key := range after {
+		if _, ok := before[key]; !ok {
+			panic(exception.NewBadRequestHandler(fmt.Sprintf("field '%s' cannot be patched", key)))
+		}
+	}
+	for key, value := range before {
+		if newValue, ok := after[key]; ok && reflect.DeepEqual(newValue, value) {
+			continue
+		}
+		if key == "id" {
+			panic(exception.NewBadRequestHandler("field 'id' cannot be patched"))
+		}
+		changed = append(changed, fieldNames[key])
+	}
+
+	return changed
 }
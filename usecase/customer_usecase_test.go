@@ -0,0 +1,455 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http/httptest"
+	"reflect"
+	"scylla/entity"
+	"scylla/model"
+	"scylla/pkg/exception"
+	"scylla/pkg/tabular"
+	"scylla/pkg/utils"
+	"scylla/repo"
+	"scylla/repo/repotest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/xuri/excelize/v2"
+)
+
+// fakeCustomerRepo counts the import queries, the other methods are not used by the import.
+type fakeCustomerRepo struct {
+	repo.CustomerRepo
+	existingCalls int
+	insertCalls   int
+	existing      map[string]bool
+}
+
+func (customers *fakeCustomerRepo) ExistingValues(ctx context.Context, column string, values []string) (map[string]bool, error) {
+	customers.existingCalls++
+	return customers.existing, nil
+}
+
+func (customers *fakeCustomerRepo) InsertBatch(ctx context.Context, data []model.Customer, batchSize int, onConflict string) (entity.BatchResult, error) {
+	customers.insertCalls++
+	return entity.BatchResult{Inserted: len(data)}, nil
+}
+
+// uploadRequest wraps content in the multipart file header an import receives.
+func uploadRequest(t *testing.T, name string, content string) entity.UploadCustomerRequest {
+	t.Helper()
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file", name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write([]byte(content))
+	form.Close()
+
+	request := httptest.NewRequest("POST", "/customers/import", &body)
+	request.Header.Set("Content-Type", form.FormDataContentType())
+	if err := request.ParseMultipartForm(1 << 20); err != nil {
+		t.Fatal(err)
+	}
+	return entity.UploadCustomerRequest{File: request.MultipartForm.File["file"][0]}
+}
+
+func TestImportErrorReportFromOnePass(t *testing.T) {
+	tests := []struct {
+		name          string
+		content       string
+		existingCalls int
+		annotated     string
+	}{
+		{
+			name:          "invalid row",
+			content:       "username,email,phone,address\nJohn,not-an-email,0812,Street\nJane,jane@example.com,0813,Road\n",
+			existingCalls: 1,
+			annotated:     "B2",
+		},
+		{
+			name:          "taken email",
+			content:       "username,email,phone,address\nJohn,taken@example.com,0812,Street\n",
+			existingCalls: 1,
+			annotated:     "B2",
+		},
+		{
+			name:      "broken header",
+			content:   "username,email,phone,fax\nJohn,john@example.com,0812,123\n",
+			annotated: "D1",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			customers := &fakeCustomerRepo{existing: map[string]bool{"taken@example.com": true}}
+			customerUsecase := NewCustomerUsecaseImpl(customers, utils.InitializeValidator(nil))
+
+			var report bytes.Buffer
+			_, err := customerUsecase.Import(context.Background(), uploadRequest(t, "customers.csv", test.content), &report)
+			if _, invalid := err.(*exception.ExcelValidation); !invalid {
+				t.Fatalf("Import() error = %v, want the validation errors", err)
+			}
+			if customers.existingCalls != test.existingCalls || customers.insertCalls != 0 {
+				t.Errorf("%d existing value lookups and %d inserts, want %d and none", customers.existingCalls, customers.insertCalls, test.existingCalls)
+			}
+
+			excel, err := excelize.OpenReader(&report)
+			if err != nil {
+				t.Fatalf("report is not a workbook: %v", err)
+			}
+			defer excel.Close()
+			comments, err := excel.GetComments(customerImportSheet)
+			if err != nil {
+				t.Fatal(err)
+			}
+			found := false
+			for _, comment := range comments {
+				found = found || comment.Cell == test.annotated
+			}
+			if !found {
+				t.Errorf("comments = %+v, want one on %s", comments, test.annotated)
+			}
+		})
+	}
+}
+
+func TestImportWithoutReport(t *testing.T) {
+	customers := &fakeCustomerRepo{}
+	customerUsecase := NewCustomerUsecaseImpl(customers, utils.InitializeValidator(nil))
+
+	content := "username,email,phone,address\nJohn,not-an-email,0812,Street\n"
+	if _, err := customerUsecase.Import(context.Background(), uploadRequest(t, "customers.csv", content), nil); err == nil {
+		t.Fatal("Import() error = nil for an invalid row")
+	}
+
+	// a valid file is inserted and leaves the report untouched
+	var report bytes.Buffer
+	content = "username,email,phone,address\nJohn,john@example.com,0812,Street\n"
+	result, err := customerUsecase.Import(context.Background(), uploadRequest(t, "customers.csv", content), &report)
+	if err != nil || result.Inserted != 1 || report.Len() != 0 {
+		t.Errorf("Import() = %+v, %v with a %d byte report, want one insert and no report", result, err, report.Len())
+	}
+}
+
+func TestExportFailureWritesNothing(t *testing.T) {
+	customers := &streamingCustomerRepo{
+		customers: []entity.CustomerResponse{{ID: 1, Username: "john", Email: "john@example.com", Phone: "0812", Address: "Street"}},
+		err:       errors.New("connection reset"),
+	}
+	customerUsecase := NewCustomerUsecaseImpl(customers, utils.InitializeValidator(nil))
+
+	for _, format := range []string{tabular.FormatXlsx, tabular.FormatCsv, tabular.FormatJsonl} {
+		t.Run(format, func(t *testing.T) {
+			var output bytes.Buffer
+			err := customerUsecase.Export(context.Background(), entity.CustomerQueryFilter{}, format, tabular.Options{}, &output)
+			if _, ok := err.(*exception.InternalServerErrorStruct); !ok {
+				t.Errorf("Export() error = %v, want an internal server error", err)
+			}
+			if output.Len() != 0 {
+				t.Errorf("output = %d bytes, want none so the error response can still be sent", output.Len())
+			}
+		})
+	}
+}
+
+// badRequest runs fn and returns the message of the 400 it panics with.
+func badRequest(t *testing.T, fn func()) (message string) {
+	t.Helper()
+	defer func() {
+		exception, ok := recover().(*exception.BadRequestStruct)
+		if !ok {
+			t.Fatal("did not panic with a bad request error")
+		}
+		message = exception.Error()
+	}()
+	fn()
+	return ""
+}
+
+// statements returns the recorded statements starting with prefix.
+func statements(recorder *repotest.Driver, prefix string) []repotest.Query {
+	var found []repotest.Query
+	for _, query := range recorder.Queries {
+		if strings.HasPrefix(query.SQL, prefix) {
+			found = append(found, query)
+		}
+	}
+	return found
+}
+
+// storedCustomers answers FindById with customer id and "0812" as the phone of every customer.
+func storedCustomers(query string, vars []interface{}) ([]string, [][]driver.Value) {
+	if !strings.Contains(query, `"customers"."id" = $1`) {
+		return nil, nil
+	}
+	id := vars[0].(int64)
+	return []string{"id", "username", "email", "phone", "address", "version"},
+		[][]driver.Value{{id, "john", fmt.Sprintf("john%d@example.com", id), "0812", "Street", int64(3)}}
+}
+
+func TestBulkUpdateItems(t *testing.T) {
+	for _, dryRun := range []bool{false, true} {
+		t.Run(fmt.Sprintf("dry run %v", dryRun), func(t *testing.T) {
+			db, recorder := repotest.Open(t)
+			recorder.Rows, recorder.RowsAffected = storedCustomers, 1
+			customerUsecase := NewCustomerUsecaseImpl(repo.NewCustomerRepoImpl(db), utils.InitializeValidator(db))
+
+			response := customerUsecase.BulkUpdate(context.Background(), entity.BulkUpdateCustomerRequest{
+				Items: []entity.BulkUpdateCustomerItem{
+					{ID: 1, Fields: map[string]string{"phone": "0899"}},
+					{ID: 2, Fields: map[string]string{"phone": "0812"}},
+				},
+				DryRun: dryRun,
+			})
+			if response.Matched != 2 || response.Changed != 1 || response.DryRun != dryRun {
+				t.Errorf("BulkUpdate() = %+v, want 2 matched and 1 changed", response)
+			}
+
+			updates := statements(recorder, "UPDATE")
+			if dryRun && len(updates) != 0 {
+				t.Errorf("dry run sent %d updates, want none", len(updates))
+			}
+			if !dryRun && (len(updates) != 1 || !strings.Contains(updates[0].SQL, `"version"=version + 1`)) {
+				t.Errorf("updates = %+v, want customer 1 alone updated with its version bumped", updates)
+			}
+		})
+	}
+}
+
+func TestBulkUpdateFilter(t *testing.T) {
+	for _, dryRun := range []bool{false, true} {
+		t.Run(fmt.Sprintf("dry run %v", dryRun), func(t *testing.T) {
+			db, recorder := repotest.Open(t)
+			// 5 customers match the filter, 3 of them do not live in Jakarta yet
+			recorder.Rows = func(query string, vars []interface{}) ([]string, [][]driver.Value) {
+				if strings.Contains(query, "IS DISTINCT FROM") {
+					return []string{"count"}, [][]driver.Value{{int64(3)}}
+				}
+				return []string{"count"}, [][]driver.Value{{int64(5)}}
+			}
+			recorder.RowsAffected = 3
+			customerUsecase := NewCustomerUsecaseImpl(repo.NewCustomerRepoImpl(db), utils.InitializeValidator(db))
+
+			response := customerUsecase.BulkUpdate(context.Background(), entity.BulkUpdateCustomerRequest{
+				Filter: &entity.CustomerQueryFilter{Username: "john"},
+				Set:    map[string]string{"address": "Jakarta"},
+				DryRun: dryRun,
+			})
+			if response.Matched != 5 || response.Changed != 3 || response.DryRun != dryRun {
+				t.Errorf("BulkUpdate() = %+v, want 5 matched and 3 changed", response)
+			}
+
+			updates := statements(recorder, "UPDATE")
+			if dryRun && len(updates) != 0 {
+				t.Errorf("dry run sent %d updates, want none", len(updates))
+			}
+			if !dryRun && (len(updates) != 1 || !strings.Contains(updates[0].SQL, "address IS DISTINCT FROM")) {
+				t.Errorf("updates = %+v, want one update leaving customers already in Jakarta alone", updates)
+			}
+			if last := recorder.Queries[len(recorder.Queries)-1].SQL; dryRun && last != "ROLLBACK" {
+				t.Errorf("last statement = %q, want the dry run rolled back", last)
+			}
+		})
+	}
+}
+
+func TestBulkUpdateRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		request entity.BulkUpdateCustomerRequest
+	}{
+		{
+			name:    "empty filter",
+			request: entity.BulkUpdateCustomerRequest{Filter: &entity.CustomerQueryFilter{}, Set: map[string]string{"address": "Jakarta"}},
+		},
+		{
+			name:    "email by filter",
+			request: entity.BulkUpdateCustomerRequest{Filter: &entity.CustomerQueryFilter{Username: "john"}, Set: map[string]string{"email": "john@example.com"}},
+		},
+		{
+			name:    "items and filter",
+			request: entity.BulkUpdateCustomerRequest{Items: []entity.BulkUpdateCustomerItem{{ID: 1}}, Filter: &entity.CustomerQueryFilter{Username: "john"}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, recorder := repotest.Open(t)
+			customerUsecase := NewCustomerUsecaseImpl(repo.NewCustomerRepoImpl(db), utils.InitializeValidator(db))
+
+			badRequest(t, func() { customerUsecase.BulkUpdate(context.Background(), test.request) })
+			if len(recorder.Queries) != 0 {
+				t.Errorf("sent %d statements, want none", len(recorder.Queries))
+			}
+		})
+	}
+}
+
+func TestBulkUpdateEmailTaken(t *testing.T) {
+	tests := []struct {
+		name    string
+		request entity.BulkUpdateCustomerRequest
+	}{
+		{
+			name:    "items",
+			request: entity.BulkUpdateCustomerRequest{Items: []entity.BulkUpdateCustomerItem{{ID: 1, Fields: map[string]string{"phone": "0899"}}}},
+		},
+		{
+			name:    "filter",
+			request: entity.BulkUpdateCustomerRequest{Filter: &entity.CustomerQueryFilter{Username: "john"}, Set: map[string]string{"address": "Jakarta"}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, recorder := repotest.Open(t)
+			recorder.Rows = storedCustomers
+			recorder.ExecErr = &pgconn.PgError{Code: "23505", ConstraintName: "unique_email"}
+			customerUsecase := NewCustomerUsecaseImpl(repo.NewCustomerRepoImpl(db), utils.InitializeValidator(db))
+
+			message := badRequest(t, func() { customerUsecase.BulkUpdate(context.Background(), test.request) })
+			if message != repo.ErrEmailTaken.Error() {
+				t.Errorf("message = %q, want %q", message, repo.ErrEmailTaken.Error())
+			}
+		})
+	}
+}
+
+func TestChangedCustomerFields(t *testing.T) {
+	original := []byte(`{"id":1,"username":"john","email":"john@example.com","phone":"0812","address":"Street"}`)
+
+	changed := changedCustomerFields(original, []byte(`{"id":1,"username":"john","email":"jane@example.com","phone":"0899","address":"Street"}`))
+	sort.Strings(changed)
+	if !reflect.DeepEqual(changed, []string{"Email", "Phone"}) {
+		t.Errorf("changed = %v, want Email and Phone", changed)
+	}
+
+	// a removed field counts as changed, so its required rule still applies
+	changed = changedCustomerFields(original, []byte(`{"id":1,"username":"john","email":"john@example.com","phone":"0812"}`))
+	if !reflect.DeepEqual(changed, []string{"Address"}) {
+		t.Errorf("changed = %v, want Address", changed)
+	}
+
+	for _, patched := range []string{
+		`{"id":2,"username":"john","email":"john@example.com","phone":"0812","address":"Street"}`,
+		`{"id":1,"username":"john","email":"john@example.com","phone":"0812","address":"Street","fax":"0813"}`,
+		`["not", "an", "object"]`,
+	} {
+		badRequest(t, func() { changedCustomerFields(original, []byte(patched)) })
+	}
+}
+
+func TestPatch(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		patch       string
+		update      string
+		invalid     bool
+	}{
+		{
+			name:        "merge patch",
+			contentType: "application/merge-patch+json",
+			patch:       `{"phone":"0899"}`,
+			update:      "0899",
+		},
+		{
+			name:        "json patch",
+			contentType: "application/json-patch+json",
+			patch:       `[{"op":"replace","path":"/phone","value":"0899"}]`,
+			update:      "0899",
+		},
+		{
+			name:        "nothing changed",
+			contentType: "application/merge-patch+json",
+			patch:       `{"phone":"0812"}`,
+		},
+		{
+			name:        "invalid changed field",
+			contentType: "application/merge-patch+json",
+			patch:       `{"email":"not-an-email"}`,
+			invalid:     true,
+		},
+		{
+			name:        "removed required field",
+			contentType: "application/merge-patch+json",
+			patch:       `{"address":null}`,
+			invalid:     true,
+		},
+		{
+			name:        "id",
+			contentType: "application/merge-patch+json",
+			patch:       `{"id":2}`,
+			invalid:     true,
+		},
+		{
+			name:        "id by json patch",
+			contentType: "application/json-patch+json",
+			patch:       `[{"op":"replace","path":"/id","value":2}]`,
+			invalid:     true,
+		},
+		{
+			name:        "unknown field",
+			contentType: "application/merge-patch+json",
+			patch:       `{"fax":"0813"}`,
+			invalid:     true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, recorder := repotest.Open(t)
+			// the stored username breaks its max rule, which only matters when a patch changes it
+			recorder.Rows = func(query string, vars []interface{}) ([]string, [][]driver.Value) {
+				if !strings.Contains(query, `"customers"."id" = $1`) {
+					return nil, nil
+				}
+				return []string{"id", "username", "email", "phone", "address", "version"},
+					[][]driver.Value{{int64(1), strings.Repeat("j", 200), "john@example.com", "0812", "Street", int64(3)}}
+			}
+			recorder.RowsAffected = 1
+			customerUsecase := NewCustomerUsecaseImpl(repo.NewCustomerRepoImpl(db), utils.InitializeValidator(db))
+			request := entity.PatchCustomerRequest{ID: 1, Version: 3, ContentType: test.contentType, Patch: []byte(test.patch)}
+
+			if test.invalid {
+				defer func() {
+					if recover() == nil {
+						t.Error("Patch() did not reject the patch")
+					}
+					if updates := statements(recorder, "UPDATE"); len(updates) != 0 {
+						t.Errorf("sent %d updates, want none", len(updates))
+					}
+				}()
+			}
+
+			version := customerUsecase.Patch(context.Background(), request)
+
+			updates := statements(recorder, "UPDATE")
+			if test.update == "" {
+				if version != 3 || len(updates) != 0 {
+					t.Errorf("Patch() = version %d with %d updates, want version 3 and no update", version, len(updates))
+				}
+				return
+			}
+			if version != 4 || len(updates) != 1 {
+				t.Fatalf("Patch() = version %d with %d updates, want version 4 and one update", version, len(updates))
+			}
+			found := false
+			for _, value := range updates[0].Vars {
+				found = found || value == test.update
+			}
+			if !found {
+				t.Errorf("update vars = %v, want %q among them", updates[0].Vars, test.update)
+			}
+		})
+	}
+}
@@ -0,0 +1,397 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"log"
+	"os"
+	"path/filepath"
+	"scylla/entity"
+	"scylla/model"
+	"scylla/pkg/exception"
+	"scylla/pkg/helper"
+	"scylla/pkg/tabular"
+	"scylla/repo"
+	"time"
+)
+
+type ExportJobUsecase interface {
+	Create(ctx context.Context, userId int, dataFilter entity.CustomerQueryFilter, format string, options tabular.Options) (response entity.ExportJobResponse)
+	FindById(ctx context.Context, userId int, request entity.ExportJobParams) (response entity.ExportJobResponse)
+	Download(ctx context.Context, userId int, request entity.ExportJobParams) (filePath string, contentType string)
+	Start(ctx context.Context) error
+}
+
+const (
+	// exportJobTimeout bounds a single export, the HTTP timeout no longer applies to it
+	exportJobTimeout = time.Hour
+	// exportJobSweep is how often expired files are removed and queued jobs picked up again
+	exportJobSweep = time.Minute
+	// exportProgressEvery is how many rows are written between two progress updates
+	exportProgressEvery = 1000
+	// exportJobHeartbeat is how often a worker marks its running job alive
+	exportJobHeartbeat = 30 * time.Second
+	// exportJobLease is how long a running job may go without a heartbeat before any instance
+	// fails it as abandoned
+	exportJobLease = 4 * exportJobHeartbeat
+)
+
+// ExportJobUsecaseImpl runs customer exports in the background on a fixed number of workers and
+// keeps the finished files in storageDir for ttl. Files live on local disk, so a job records the
+// instance that ran it and only that instance serves and removes its file; with several instances
+// downloads have to be routed to the one named by the job, or storageDir has to be shared.
+type ExportJobUsecaseImpl struct {
+	exportJobRepo repo.ExportJobRepo
+	customerRepo  repo.CustomerRepo
+	validate      *validator.Validate
+	storageDir    string
+	ttl           time.Duration
+	workers       int
+	queue         chan string
+	// instance names this process on the jobs it runs, the host name
+	instance string
+}
+
+func NewExportJobUsecaseImpl(exportJobRepo repo.ExportJobRepo, customerRepo repo.CustomerRepo, validate *validator.Validate, storageDir string, ttl time.Duration, workers int) ExportJobUsecase {
+	if workers < 1 {
+		workers = 1
+	}
+
+	// a replaced container comes back with a new host name and an empty disk, which is what a
+	// different instance means for the files
+	instance, err := os.Hostname()
+	if err != nil {
+		log.Printf("export jobs: %v", err)
+	}
+
+	return &ExportJobUsecaseImpl{
+		exportJobRepo: exportJobRepo,
+		customerRepo:  customerRepo,
+		validate:      validate,
+		storageDir:    storageDir,
+		ttl:           ttl,
+		workers:       workers,
+		queue:         make(chan string, 100),
+		instance:      instance,
+	}
+}
+
+// Create queues an export of the customers matching dataFilter, format and options are kept on the
+// job and applied when it runs, like they are for a synchronous export.
+func (usecase *ExportJobUsecaseImpl) Create(ctx context.Context, userId int, dataFilter entity.CustomerQueryFilter, format string, options tabular.Options) (response entity.ExportJobResponse) {
+	// a bad filter or sort is rejected now instead of failing the job later
+	err := repo.CheckCustomerFilter(dataFilter)
+	helper.ErrorPanic(err)
+
+	total, err := usecase.customerRepo.Count(ctx, dataFilter)
+	if err != nil {
+		panic(exception.NewInternalServerErrorHandler(err.Error()))
+	}
+
+	filter, err := json.Marshal(dataFilter)
+	if err != nil {
+		panic(exception.NewInternalServerErrorHandler(err.Error()))
+	}
+
+	job := model.ExportJob{
+		ID:        uuid.New().String(),
+		UserID:    userId,
+		State:     model.ExportJobQueued,
+		Filter:    string(filter),
+		Format:    format,
+		Encoding:  options.Encoding,
+		TotalRows: total,
+		CreatedAt: time.Now(),
+	}
+	if options.Delimiter != 0 {
+		job.Delimiter = string(options.Delimiter)
+	}
+
+	err = usecase.exportJobRepo.Insert(ctx, job)
+	if err != nil {
+		panic(exception.NewInternalServerErrorHandler(err.Error()))
+	}
+
+	usecase.enqueue(job.ID)
+
+	return exportJobResponse(job)
+}
+
+func (usecase *ExportJobUsecaseImpl) FindById(ctx context.Context, userId int, request entity.ExportJobParams) (response entity.ExportJobResponse) {
+	return exportJobResponse(usecase.findOwn(ctx, userId, request))
+}
+
+func (usecase *ExportJobUsecaseImpl) Download(ctx context.Context, userId int, request entity.ExportJobParams) (filePath string, contentType string) {
+	job := usecase.findOwn(ctx, userId, request)
+
+	// a job of an instance that is gone is never swept, its file went with the instance
+	expired := job.ExpiresAt != nil && job.ExpiresAt.Before(time.Now())
+
+	switch {
+	case job.State == model.ExportJobCompleted && !expired && job.Instance != usecase.instance:
+		panic(exception.NewNotFoundHandler(fmt.Sprintf("export file is kept by instance %s", job.Instance)))
+	case job.State == model.ExportJobCompleted && !expired:
+		options, err := tabular.ParseOptions(job.Delimiter, job.Encoding)
+		helper.ErrorPanic(err)
+		return job.FilePath, tabular.ContentType(job.Format, options)
+	case job.State == model.ExportJobExpired, job.State == model.ExportJobCompleted:
+		panic(exception.NewNotFoundHandler("export has expired"))
+	default:
+		panic(exception.NewBadRequestHandler(fmt.Sprintf("export is %s, not ready for download", job.State)))
+	}
+}
+
+// Start prepares the storage directory and starts the workers and the sweeper, which stop with
+// ctx. Several instances may share the jobs table, so jobs left running by a stopped instance are
+// only failed by the sweeper once their heartbeat is older than exportJobLease, and every
+// instance only removes the files it wrote.
+func (usecase *ExportJobUsecaseImpl) Start(ctx context.Context) error {
+	if err := os.MkdirAll(usecase.storageDir, 0o755); err != nil {
+		return err
+	}
+
+	for i := 0; i < usecase.workers; i++ {
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case id := <-usecase.queue:
+					usecase.run(id)
+				}
+			}
+		}()
+	}
+
+	go func() {
+		ticker := time.NewTicker(exportJobSweep)
+		defer ticker.Stop()
+		for {
+			usecase.sweep(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (usecase *ExportJobUsecaseImpl) findOwn(ctx context.Context, userId int, request entity.ExportJobParams) model.ExportJob {
+	err := usecase.validate.Struct(request)
+	helper.ErrorPanic(err)
+
+	// someone else's job is reported as missing rather than forbidden, so ids cannot be probed
+	job, err := usecase.exportJobRepo.FindById(ctx, request.ExportId)
+	if err != nil || job.UserID != userId {
+		panic(exception.NewNotFoundHandler("record not found"))
+	}
+
+	return job
+}
+
+// enqueue hands the job to a worker without blocking, a full queue leaves it queued for the sweeper.
+func (usecase *ExportJobUsecaseImpl) enqueue(id string) {
+	select {
+	case usecase.queue <- id:
+	default:
+	}
+}
+
+func (usecase *ExportJobUsecaseImpl) run(id string) {
+	ctx, cancel := context.WithTimeout(context.Background(), exportJobTimeout)
+	defer cancel()
+
+	claimed, err := usecase.exportJobRepo.Claim(ctx, id, usecase.instance)
+	if err != nil || !claimed {
+		return
+	}
+
+	beat, stop := context.WithCancel(ctx)
+	defer stop()
+	go usecase.heartbeat(beat, id)
+
+	job, err := usecase.exportJobRepo.FindById(ctx, id)
+	if err != nil {
+		return
+	}
+
+	job.State = model.ExportJobRunning
+	filePath, err := usecase.export(ctx, &job)
+
+	now := time.Now()
+	expiresAt := now.Add(usecase.ttl)
+	job.FinishedAt = &now
+	job.ExpiresAt = &expiresAt
+	if err != nil {
+		log.Printf("export job %s: %v", id, err)
+		job.State = model.ExportJobFailed
+		job.Error = exportJobError(err)
+	} else {
+		job.State = model.ExportJobCompleted
+		job.FilePath = filePath
+	}
+
+	if err := usecase.exportJobRepo.Update(context.Background(), job); err != nil {
+		log.Printf("export job %s: %v", id, err)
+	}
+}
+
+// export writes the file to a temporary file that is only renamed into place once complete,
+// so a download never sees half a file.
+func (usecase *ExportJobUsecaseImpl) export(ctx context.Context, job *model.ExportJob) (filePath string, err error) {
+	// the filter and repo report bad input by panicking, which must not take the worker down
+	defer func() {
+		if r := recover(); r != nil {
+			if panicErr, ok := r.(error); ok {
+				err = panicErr
+			} else {
+				err = fmt.Errorf("%v", r)
+			}
+		}
+	}()
+
+	var dataFilter entity.CustomerQueryFilter
+	if err := json.Unmarshal([]byte(job.Filter), &dataFilter); err != nil {
+		return "", err
+	}
+
+	options, err := tabular.ParseOptions(job.Delimiter, job.Encoding)
+	if err != nil {
+		return "", err
+	}
+	options.Sheet = customerImportSheet
+
+	filePath = filepath.Join(usecase.storageDir, job.ID+"."+job.Format)
+	tmpPath := filePath + ".tmp"
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmpPath)
+
+	table, err := tabular.NewWriter(job.Format, file, customerColumns, options)
+	if err != nil {
+		file.Close()
+		return "", err
+	}
+
+	err = writeCustomers(ctx, usecase.customerRepo, dataFilter, table, func(rows int) error {
+		job.RowsWritten = rows
+		if rows%exportProgressEvery != 0 {
+			return nil
+		}
+		return usecase.exportJobRepo.UpdateProgress(ctx, job.ID, rows)
+	})
+	if err != nil {
+		table.Abort()
+	} else {
+		err = table.Close()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", err
+	}
+
+	// rows may have changed since the job was created, the final count is what was written
+	job.TotalRows = job.RowsWritten
+
+	return filePath, os.Rename(tmpPath, filePath)
+}
+
+// heartbeat keeps the job's lease until ctx ends.
+func (usecase *ExportJobUsecaseImpl) heartbeat(ctx context.Context, id string) {
+	ticker := time.NewTicker(exportJobHeartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := usecase.exportJobRepo.Heartbeat(ctx, id); err != nil && ctx.Err() == nil {
+				log.Printf("export job %s: %v", id, err)
+			}
+		}
+	}
+}
+
+func (usecase *ExportJobUsecaseImpl) sweep(ctx context.Context) {
+	now := time.Now()
+	err := usecase.exportJobRepo.FailStale(ctx, "interrupted, its worker stopped", now.Add(-exportJobLease), now.Add(usecase.ttl))
+	if err != nil {
+		log.Printf("export jobs: %v", err)
+	}
+
+	expired, err := usecase.exportJobRepo.FindExpired(ctx, time.Now(), usecase.instance)
+	if err != nil {
+		log.Printf("export jobs: %v", err)
+	}
+	for _, job := range expired {
+		if job.FilePath != "" {
+			if err := os.Remove(job.FilePath); err != nil && !os.IsNotExist(err) {
+				log.Printf("export job %s: %v", job.ID, err)
+				continue
+			}
+		}
+
+		job.State = model.ExportJobExpired
+		job.FilePath = ""
+		if err := usecase.exportJobRepo.Update(ctx, job); err != nil {
+			log.Printf("export job %s: %v", job.ID, err)
+		}
+	}
+
+	queued, err := usecase.exportJobRepo.FindQueued(ctx)
+	if err != nil {
+		log.Printf("export jobs: %v", err)
+	}
+	for _, id := range queued {
+		usecase.enqueue(id)
+	}
+}
+
+// exportJobError is what the client is told about a failed job: the message of a bad request, and
+// for anything else only that it failed, the details go to the log like any internal error.
+func exportJobError(err error) string {
+	if badRequest, ok := err.(*exception.BadRequestStruct); ok {
+		return badRequest.Error()
+	}
+	return "export failed"
+}
+
+func exportJobResponse(job model.ExportJob) entity.ExportJobResponse {
+	response := entity.ExportJobResponse{
+		ID:          job.ID,
+		State:       job.State,
+		Format:      job.Format,
+		TotalRows:   job.TotalRows,
+		RowsWritten: job.RowsWritten,
+		Error:       job.Error,
+		CreatedAt:   job.CreatedAt.Format(time.RFC3339),
+	}
+
+	switch {
+	case job.State == model.ExportJobCompleted || job.State == model.ExportJobExpired:
+		response.Percent = 100
+	case job.TotalRows > 0:
+		response.Percent = min(99, job.RowsWritten*100/job.TotalRows)
+	}
+
+	if job.FinishedAt != nil {
+		finishedAt := job.FinishedAt.Format(time.RFC3339)
+		response.FinishedAt = &finishedAt
+	}
+	if job.ExpiresAt != nil {
+		expiresAt := job.ExpiresAt.Format(time.RFC3339)
+		response.ExpiresAt = &expiresAt
+	}
+
+	return response
+}
@@ -0,0 +1,146 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"scylla/entity"
+	"scylla/model"
+	"scylla/pkg/exception"
+	"scylla/pkg/utils"
+	"scylla/repo"
+	"testing"
+	"time"
+)
+
+// streamingCustomerRepo streams a fixed list of customers and then fails with err when set, the
+// other methods are not used by an export.
+type streamingCustomerRepo struct {
+	repo.CustomerRepo
+	customers []entity.CustomerResponse
+	err       error
+}
+
+func (customers *streamingCustomerRepo) StreamAll(ctx context.Context, dataFilter entity.CustomerQueryFilter, fn func(customer entity.CustomerResponse) error) error {
+	for _, customer := range customers.customers {
+		if err := fn(customer); err != nil {
+			return err
+		}
+	}
+	return customers.err
+}
+
+func TestExportUsesJobFormat(t *testing.T) {
+	usecase := &ExportJobUsecaseImpl{
+		customerRepo: &streamingCustomerRepo{customers: []entity.CustomerResponse{
+			{ID: 1, Username: "john", Email: "john@example.com", Phone: "0812", Address: "Street"},
+		}},
+		storageDir: t.TempDir(),
+	}
+	job := model.ExportJob{ID: "a5b7c3d1-0000-4000-8000-000000000001", Filter: "{}", Format: "csv", Delimiter: ";", Encoding: "utf-8"}
+
+	filePath, err := usecase.export(context.Background(), &job)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Ext(filePath) != ".csv" {
+		t.Errorf("file = %s, want a .csv file", filePath)
+	}
+
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "ID;Name;Email;Phone;Address\n1;john;john@example.com;0812;Street\n"
+	if string(content) != want {
+		t.Errorf("content = %q, want %q", content, want)
+	}
+	if job.RowsWritten != 1 {
+		t.Errorf("rows written = %d, want 1", job.RowsWritten)
+	}
+}
+
+// fakeExportJobRepo hands out job and keeps the last update, for a single run.
+type fakeExportJobRepo struct {
+	repo.ExportJobRepo
+	job     model.ExportJob
+	updated model.ExportJob
+}
+
+func (jobs *fakeExportJobRepo) Claim(ctx context.Context, Id string, instance string) (bool, error) {
+	return true, nil
+}
+
+func (jobs *fakeExportJobRepo) FindById(ctx context.Context, Id string) (model.ExportJob, error) {
+	return jobs.job, nil
+}
+
+func (jobs *fakeExportJobRepo) Update(ctx context.Context, data model.ExportJob) error {
+	jobs.updated = data
+	return nil
+}
+
+func (jobs *fakeExportJobRepo) Heartbeat(ctx context.Context, Id string) error {
+	return nil
+}
+
+func TestFailedExportHidesInternalErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"internal error", errors.New("pq: connection refused to 10.0.0.5"), "export failed"},
+		{"bad request", exception.NewBadRequestHandler("field 'password' is not allowed"), "field 'password' is not allowed"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			jobs := &fakeExportJobRepo{job: model.ExportJob{ID: "a5b7c3d1-0000-4000-8000-000000000002", Filter: "{}", Format: "csv"}}
+			usecase := &ExportJobUsecaseImpl{
+				exportJobRepo: jobs,
+				customerRepo:  &streamingCustomerRepo{err: test.err},
+				storageDir:    t.TempDir(),
+			}
+
+			usecase.run(jobs.job.ID)
+			if jobs.updated.State != model.ExportJobFailed || jobs.updated.Error != test.want {
+				t.Errorf("job = %s with error %q, want failed with %q", jobs.updated.State, jobs.updated.Error, test.want)
+			}
+		})
+	}
+}
+
+func TestDownloadOnlyFromTheRunningInstance(t *testing.T) {
+	expiresAt := time.Now().Add(time.Hour)
+	expiredAt := time.Now().Add(-time.Hour)
+	tests := []struct {
+		name     string
+		instance string
+		expires  *time.Time
+		served   bool
+	}{
+		{"same instance", "host-a", &expiresAt, true},
+		{"other instance", "host-b", &expiresAt, false},
+		{"past expiry", "host-a", &expiredAt, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			jobs := &fakeExportJobRepo{job: model.ExportJob{
+				ID: "a5b7c3d1-0000-4000-8000-000000000003", UserID: 1, State: model.ExportJobCompleted, Format: "csv",
+				FilePath: "/exports/job.csv", Instance: test.instance, ExpiresAt: test.expires,
+			}}
+			usecase := &ExportJobUsecaseImpl{exportJobRepo: jobs, validate: utils.InitializeValidator(nil), instance: "host-a"}
+
+			defer func() {
+				_, notFound := recover().(*exception.NotFoundStruct)
+				if notFound == test.served {
+					t.Errorf("download answered not found = %v, want %v", notFound, !test.served)
+				}
+			}()
+			usecase.Download(context.Background(), 1, entity.ExportJobParams{ExportId: jobs.job.ID})
+		})
+	}
+}